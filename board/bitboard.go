@@ -0,0 +1,279 @@
+package board
+
+import "math/bits"
+
+// Bitboard is a set of squares, one bit per square. Bit row*8+column is
+// set for the square at Squares[row][column], so bit 0 is A8 and bit 63 is
+// H1.
+type Bitboard uint64
+
+func squareIndex(sq *Square) int {
+	return sq.Row*8 + sq.Column
+}
+
+func squareBit(idx int) Bitboard {
+	return Bitboard(1) << idx
+}
+
+// Has reports whether the square at idx is in the set.
+func (bb Bitboard) Has(idx int) bool {
+	return bb&squareBit(idx) != 0
+}
+
+// Count returns the number of squares in the set.
+func (bb Bitboard) Count() int {
+	return bits.OnesCount64(uint64(bb))
+}
+
+// first returns the lowest square index in a non-empty set.
+func (bb Bitboard) first() int {
+	return bits.TrailingZeros64(uint64(bb))
+}
+
+// last returns the highest square index in a non-empty set.
+func (bb Bitboard) last() int {
+	return 63 - bits.LeadingZeros64(uint64(bb))
+}
+
+// Squares returns the board squares in the set, lowest index first.
+func (bb Bitboard) Squares(b *Board) []*Square {
+	squares := make([]*Square, 0, bb.Count())
+	for ; bb != 0; bb &= bb - 1 {
+		squares = append(squares, b.squareAt(bb.first()))
+	}
+	return squares
+}
+
+// rankMask returns the squares of board row row.
+func rankMask(row int) Bitboard {
+	return Bitboard(0xff) << (row * 8)
+}
+
+func (b *Board) squareAt(idx int) *Square {
+	return b.Squares[idx/8][idx%8]
+}
+
+const (
+	WHITE_INDEX = iota
+	BLACK_INDEX
+)
+
+const (
+	PAWN_INDEX = iota
+	KNIGHT_INDEX
+	BISHOP_INDEX
+	ROOK_INDEX
+	QUEEN_INDEX
+	KING_INDEX
+)
+
+func colorIndex(color string) int {
+	if color == BLACK {
+		return BLACK_INDEX
+	}
+	return WHITE_INDEX
+}
+
+func kindIndex(pieceType string) int {
+	switch pieceType {
+	case PAWN:
+		return PAWN_INDEX
+	case KNIGHT:
+		return KNIGHT_INDEX
+	case BISHOP:
+		return BISHOP_INDEX
+	case ROOK:
+		return ROOK_INDEX
+	case QUEEN:
+		return QUEEN_INDEX
+	case KING:
+		return KING_INDEX
+	default:
+		return -1
+	}
+}
+
+// bitboards mirrors the pieces on Squares. It is rebuilt from the squares at
+// the start of every Evaluate, so code that moves pieces around on the
+// squares never has to keep it up to date.
+type bitboards struct {
+	pieces   [2][6]Bitboard
+	occupied [2]Bitboard
+	all      Bitboard
+}
+
+func (b *Board) syncBitboards() {
+	b.bb = bitboards{}
+	for row := range b.Squares {
+		for col, sq := range b.Squares[row] {
+			kind := kindIndex(sq.Piece.Type())
+			if kind < 0 {
+				continue
+			}
+			color := colorIndex(sq.Piece.Color())
+			bit := squareBit(row*8 + col)
+			b.bb.pieces[color][kind] |= bit
+			b.bb.occupied[color] |= bit
+		}
+	}
+	b.bb.all = b.bb.occupied[WHITE_INDEX] | b.bb.occupied[BLACK_INDEX]
+}
+
+// Directions in board coordinates, ordered so that the first four move to
+// higher square indexes and the last four to lower ones.
+var rayDirs = [8][2]int{
+	{0, 1},   // E
+	{1, -1},  // SW
+	{1, 0},   // S
+	{1, 1},   // SE
+	{0, -1},  // W
+	{-1, 1},  // NE
+	{-1, 0},  // N
+	{-1, -1}, // NW
+}
+
+var (
+	rookDirs   = []int{0, 2, 4, 6}
+	bishopDirs = []int{1, 3, 5, 7}
+)
+
+var (
+	knightAttacks [64]Bitboard
+	kingAttacks   [64]Bitboard
+	pawnAttacks   [2][64]Bitboard
+	rays          [8][64]Bitboard
+	between       [64][64]Bitboard
+	lines         [64][64]Bitboard
+)
+
+func init() {
+	for idx := 0; idx < 64; idx++ {
+		row, col := idx/8, idx%8
+		for _, dir := range KNIGHT_DIRS {
+			if squareExists(row+dir[0], col+dir[1]) {
+				knightAttacks[idx] |= squareBit((row+dir[0])*8 + col + dir[1])
+			}
+		}
+		for _, dir := range rayDirs {
+			if squareExists(row+dir[0], col+dir[1]) {
+				kingAttacks[idx] |= squareBit((row+dir[0])*8 + col + dir[1])
+			}
+		}
+		for _, dc := range []int{-1, 1} {
+			if squareExists(row-1, col+dc) {
+				pawnAttacks[WHITE_INDEX][idx] |= squareBit((row-1)*8 + col + dc)
+			}
+			if squareExists(row+1, col+dc) {
+				pawnAttacks[BLACK_INDEX][idx] |= squareBit((row+1)*8 + col + dc)
+			}
+		}
+
+		for dir, delta := range rayDirs {
+			gap := Bitboard(0)
+			r, c := row+delta[0], col+delta[1]
+			for squareExists(r, c) {
+				to := r*8 + c
+				rays[dir][idx] |= squareBit(to)
+				between[idx][to] = gap
+				gap |= squareBit(to)
+				r, c = r+delta[0], c+delta[1]
+			}
+		}
+	}
+	for idx := 0; idx < 64; idx++ {
+		for dir := range rayDirs {
+			line := rays[dir][idx] | rays[(dir+4)%8][idx] | squareBit(idx)
+			for ray := rays[dir][idx]; ray != 0; ray &= ray - 1 {
+				lines[idx][ray.first()] = line
+			}
+		}
+	}
+}
+
+// rayAttacks returns the squares reached from idx in direction dir, up to
+// and including the first occupied square.
+func rayAttacks(dir, idx int, occupied Bitboard) Bitboard {
+	attacks := rays[dir][idx]
+	blockers := attacks & occupied
+	if blockers == 0 {
+		return attacks
+	}
+	blocker := blockers.first()
+	if dir >= 4 {
+		blocker = blockers.last()
+	}
+	return attacks &^ rays[dir][blocker]
+}
+
+func sliderAttacks(dirs []int, idx int, occupied Bitboard) Bitboard {
+	attacks := Bitboard(0)
+	for _, dir := range dirs {
+		attacks |= rayAttacks(dir, idx, occupied)
+	}
+	return attacks
+}
+
+func rookAttacks(idx int, occupied Bitboard) Bitboard {
+	return sliderAttacks(rookDirs, idx, occupied)
+}
+
+func bishopAttacks(idx int, occupied Bitboard) Bitboard {
+	return sliderAttacks(bishopDirs, idx, occupied)
+}
+
+// pieceAttacks returns the squares a piece of kind and color on idx attacks
+// given the occupied squares. Pawns attack their two forward diagonals.
+func pieceAttacks(kind, color, idx int, occupied Bitboard) Bitboard {
+	switch kind {
+	case PAWN_INDEX:
+		return pawnAttacks[color][idx]
+	case KNIGHT_INDEX:
+		return knightAttacks[idx]
+	case BISHOP_INDEX:
+		return bishopAttacks(idx, occupied)
+	case ROOK_INDEX:
+		return rookAttacks(idx, occupied)
+	case QUEEN_INDEX:
+		return rookAttacks(idx, occupied) | bishopAttacks(idx, occupied)
+	case KING_INDEX:
+		return kingAttacks[idx]
+	default:
+		return 0
+	}
+}
+
+// attackedBy returns every square attacked by color when occupied holds the
+// blocking pieces.
+func (b *Board) attackedBy(color int, occupied Bitboard) Bitboard {
+	pieces := &b.bb.pieces[color]
+	attacks := Bitboard(0)
+	for bb := pieces[PAWN_INDEX]; bb != 0; bb &= bb - 1 {
+		attacks |= pawnAttacks[color][bb.first()]
+	}
+	for bb := pieces[KNIGHT_INDEX]; bb != 0; bb &= bb - 1 {
+		attacks |= knightAttacks[bb.first()]
+	}
+	for bb := pieces[BISHOP_INDEX] | pieces[QUEEN_INDEX]; bb != 0; bb &= bb - 1 {
+		attacks |= bishopAttacks(bb.first(), occupied)
+	}
+	for bb := pieces[ROOK_INDEX] | pieces[QUEEN_INDEX]; bb != 0; bb &= bb - 1 {
+		attacks |= rookAttacks(bb.first(), occupied)
+	}
+	for bb := pieces[KING_INDEX]; bb != 0; bb &= bb - 1 {
+		attacks |= kingAttacks[bb.first()]
+	}
+	return attacks
+}
+
+// attackersTo returns the pieces of color that attack the square at idx when
+// occupied holds the blocking pieces.
+func (b *Board) attackersTo(idx, color int, occupied Bitboard) Bitboard {
+	pieces := &b.bb.pieces[color]
+	diagonals := pieces[BISHOP_INDEX] | pieces[QUEEN_INDEX]
+	straights := pieces[ROOK_INDEX] | pieces[QUEEN_INDEX]
+	return pawnAttacks[1-color][idx]&pieces[PAWN_INDEX] |
+		knightAttacks[idx]&pieces[KNIGHT_INDEX] |
+		kingAttacks[idx]&pieces[KING_INDEX] |
+		bishopAttacks(idx, occupied)&diagonals |
+		rookAttacks(idx, occupied)&straights
+}
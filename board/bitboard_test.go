@@ -0,0 +1,156 @@
+package board
+
+import "testing"
+
+const kiwipeteFen = "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+
+func TestAttackTables(t *testing.T) {
+	board := New()
+	sq := func(name string) int {
+		row, col, _ := parseSquareName(name)
+		return row*8 + col
+	}
+	names := func(bb Bitboard) map[string]bool {
+		found := map[string]bool{}
+		for _, square := range bb.Squares(board) {
+			found[square.Name] = true
+		}
+		return found
+	}
+
+	tests := []struct {
+		name     string
+		attacks  Bitboard
+		expected []string
+	}{
+		{"knight a1", knightAttacks[sq("a1")], []string{"B3", "C2"}},
+		{"king h8", kingAttacks[sq("h8")], []string{"G8", "G7", "H7"}},
+		{"white pawn e4", pawnAttacks[WHITE_INDEX][sq("e4")], []string{"D5", "F5"}},
+		{"black pawn a7", pawnAttacks[BLACK_INDEX][sq("a7")], []string{"B6"}},
+		{
+			"rook d4 blocked on d6 and f4",
+			rookAttacks(sq("d4"), squareBit(sq("d6"))|squareBit(sq("f4"))),
+			[]string{"D5", "D6", "E4", "F4", "C4", "B4", "A4", "D3", "D2", "D1"},
+		},
+		{
+			"bishop c1 blocked on e3",
+			bishopAttacks(sq("c1"), squareBit(sq("e3"))),
+			[]string{"B2", "A3", "D2", "E3"},
+		},
+		{"between a1 h8", between[sq("a1")][sq("h8")], []string{"B2", "C3", "D4", "E5", "F6", "G7"}},
+		{"between a1 b3", between[sq("a1")][sq("b3")], []string{}},
+	}
+
+	for _, tt := range tests {
+		found := names(tt.attacks)
+		if len(found) != len(tt.expected) {
+			t.Fatalf("%s should attack %v. Got %v", tt.name, tt.expected, found)
+		}
+		for _, name := range tt.expected {
+			if !found[name] {
+				t.Fatalf("%s should attack %s. Got %v", tt.name, name, found)
+			}
+		}
+	}
+}
+
+func TestSyncBitboards(t *testing.T) {
+	board := New()
+	board.SetupPieces()
+	board.Evaluate(BLACK)
+
+	if board.bb.all.Count() != 32 {
+		t.Fatalf("start position should have 32 pieces. Got %d", board.bb.all.Count())
+	}
+	for color := range board.bb.pieces {
+		counts := []int{8, 2, 2, 2, 1, 1}
+		for kind, count := range counts {
+			if got := board.bb.pieces[color][kind].Count(); got != count {
+				t.Fatalf("color %d kind %d should have %d pieces. Got %d", color, kind, count, got)
+			}
+		}
+	}
+	if !board.bb.pieces[WHITE_INDEX][KING_INDEX].Has(squareIndex(board.Squares[ROW_1][COL_E])) {
+		t.Fatalf("white king should be on E1")
+	}
+}
+
+func TestLegalMoveEdgeCases(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		uci      string
+		expected bool
+	}{
+		{"en passant exposing king on rank", "8/8/8/K2pP2r/8/8/8/4k3 w - d6 0 1", "e5d6", false},
+		{"en passant removing checking pawn", "8/8/8/3pP3/4K3/8/8/4k3 w - d6 0 1", "e5d6", true},
+		{"en passant blocking check", "1b2k3/8/8/2Pp4/5K2/8/8/8 w - d6 0 1", "c5d6", true},
+		{"other move while in check", "1b2k3/8/8/2Pp4/5K2/8/8/8 w - d6 0 1", "c5c6", false},
+		{"long castle with b-file piece", "4k3/8/8/8/8/8/8/RN2K3 w Q - 0 1", "e1c1", false},
+		{"long castle with attacked b-file", "1r2k3/8/8/8/8/8/8/R3K3 w Q - 0 1", "e1c1", true},
+		{"short castle through check", "4kr2/8/8/8/8/8/8/4K2R w K - 0 1", "e1g1", false},
+		{"king stepping back along check", "4k3/8/8/8/8/8/8/r3K3 w - - 0 1", "e1f1", false},
+		{"knight attacked but not pinned", "4k3/8/8/8/8/8/4N2r/4K3 w - - 0 1", "e2g3", true},
+		{"pinned knight on file", "4r2k/8/8/8/8/8/4N3/4K3 w - - 0 1", "e2g3", false},
+		{"pinned rook along pin", "4r2k/8/8/8/8/8/4R3/4K3 w - - 0 1", "e2e8", true},
+		{"pinned rook off pin", "4r2k/8/8/8/8/8/4R3/4K3 w - - 0 1", "e2a2", false},
+		{"double push from start rank", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", "e2e4", true},
+		{"double push blocked", "4k3/8/8/8/8/4n3/4P3/4K3 w - - 0 1", "e2e4", false},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		if err := board.SetupFromFen(tt.fen); err != nil {
+			t.Fatalf("%s: SetupFromFen returned error: %s", tt.name, err.Message)
+		}
+		board.Evaluate(ENEMY[board.Turn])
+		_, err := board.ParseUci(tt.uci)
+		if (err == nil) != tt.expected {
+			t.Fatalf("%s: %s legal should be %t", tt.name, tt.uci, tt.expected)
+		}
+	}
+}
+
+func benchmarkBoard(b *testing.B, fen string) *Board {
+	b.Helper()
+	board := New()
+	if err := board.SetupFromFen(fen); err != nil {
+		b.Fatalf("SetupFromFen returned error: %s", err.Message)
+	}
+	board.Evaluate(ENEMY[board.Turn])
+	return board
+}
+
+func BenchmarkEvaluate(b *testing.B) {
+	board := benchmarkBoard(b, kiwipeteFen)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.Evaluate(BLACK)
+	}
+}
+
+func BenchmarkGetAllValidMoves(b *testing.B) {
+	board := benchmarkBoard(b, kiwipeteFen)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.GetAllValidMoves(WHITE)
+	}
+}
+
+func BenchmarkMakeUndo(b *testing.B) {
+	board := benchmarkBoard(b, kiwipeteFen)
+	moves := board.GetAllValidMoves(WHITE)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.MovePiece(moves[i%len(moves)])
+		board.UndoMove()
+	}
+}
+
+func BenchmarkMiniMax(b *testing.B) {
+	board := benchmarkBoard(b, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board.MiniMax(WHITE, -1000, 1000, 3)
+	}
+}
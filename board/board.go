@@ -1,10 +1,6 @@
 package board
 
-import (
-	"fmt"
-	"strconv"
-	"strings"
-)
+import "fmt"
 
 const (
 	BLACK = "BLACK"
@@ -45,14 +41,30 @@ type Board struct {
 	Stalemate      bool
 	Draw           bool
 	Value          float64
-	Fens           map[string]int
+	Hashes         []uint64
 	Receipts       []string
+	Turn           string
+	EnPassant      *Square
+	HalfmoveClock  int
+	FullmoveNumber int
+	// TT caches search results across MiniMax calls. It is shared by
+	// copies of the board; nil searches without one.
+	TT *TranspositionTable
+
+	hash   uint64
+	bb     bitboards
+	search *searchState
+	// pawns is the board's own pawn hash, made on first use. Unlike TT it
+	// is not safe to use from two goroutines, so copies start without one.
+	pawns *pawnTable
 }
 
 func New() *Board {
 	board := &Board{
-		WhitePieces: make(map[Piece]bool),
-		BlackPieces: make(map[Piece]bool),
+		WhitePieces:    make(map[Piece]bool),
+		BlackPieces:    make(map[Piece]bool),
+		Turn:           WHITE,
+		FullmoveNumber: 1,
 	}
 
 	rows := []string{"8", "7", "6", "5", "4", "3", "2", "1"}
@@ -73,7 +85,7 @@ func New() *Board {
 		}
 		board.Squares = append(board.Squares, boardRow)
 	}
-	board.Fens = map[string]int{}
+	board.Hashes = []uint64{}
 
 	return board
 }
@@ -100,7 +112,7 @@ func (b *Board) Copy() *Board {
 				queen := &Queen{color: piece.color, value: piece.value, moveCount: piece.moveCount}
 				copy.SetPiece(queen, sq)
 			case *King:
-				king := &King{color: piece.color, value: piece.value, moveCount: piece.moveCount}
+				king := &King{color: piece.color, value: piece.value, moveCount: piece.moveCount, Castled: piece.Castled}
 				copy.SetPiece(king, sq)
 			default:
 				copy.SetPiece(&Null{}, sq)
@@ -110,17 +122,24 @@ func (b *Board) Copy() *Board {
 	for _, receipt := range b.Receipts {
 		copy.Receipts = append(copy.Receipts, receipt)
 	}
-	for fen, count := range b.Fens {
-		copy.Fens[fen] = count
-	}
+	copy.Hashes = append(copy.Hashes, b.Hashes...)
 	for _, move := range b.Moves {
 		copy.Moves = append(copy.Moves, move)
 	}
+	copy.Turn = b.Turn
+	if b.EnPassant != nil {
+		copy.EnPassant = copy.Squares[b.EnPassant.Row][b.EnPassant.Column]
+	}
+	copy.HalfmoveClock = b.HalfmoveClock
+	copy.FullmoveNumber = b.FullmoveNumber
+	copy.hash = b.hash
+	copy.TT = b.TT
 	return copy
 }
 
 type Error struct {
 	Message string
+	Err     error
 }
 
 func NewError(format string, a ...interface{}) *Error {
@@ -129,6 +148,16 @@ func NewError(format string, a ...interface{}) *Error {
 	}
 }
 
+// wrapError builds an Error whose kind can be matched with errors.Is.
+func wrapError(err error, format string, a ...interface{}) *Error {
+	boardErr := NewError(format, a...)
+	boardErr.Err = err
+	return boardErr
+}
+
+func (e *Error) Error() string { return e.Message }
+func (e *Error) Unwrap() error { return e.Err }
+
 type Square struct {
 	Piece       Piece
 	Row         int
@@ -170,43 +199,23 @@ func (b *Board) GetSquare(row, col int) *Square {
 }
 
 func (b *Board) CreatePiece(color string, name string) Piece {
+	value := PieceValues[name]
+	if color == BLACK {
+		value *= float64(-1)
+	}
 	switch name {
 	case PAWN:
-		p := &Pawn{color: color, value: 1}
-		if color == BLACK {
-			p.value *= float64(-1)
-		}
-		return p
+		return &Pawn{color: color, value: value}
 	case KNIGHT:
-		kn := &Knight{color: color, value: 3.05}
-		if color == BLACK {
-			kn.value *= float64(-1)
-		}
-		return kn
+		return &Knight{color: color, value: value}
 	case BISHOP:
-		bish := &Bishop{color: color, value: 3.33}
-		if color == BLACK {
-			bish.value *= float64(-1)
-		}
-		return bish
+		return &Bishop{color: color, value: value}
 	case ROOK:
-		r := &Rook{color: color}
-		if color == BLACK {
-			r.value *= float64(-1)
-		}
-		return r
+		return &Rook{color: color, value: value}
 	case QUEEN:
-		q := &Queen{color: color}
-		if color == BLACK {
-			q.value *= float64(-1)
-		}
-		return q
+		return &Queen{color: color, value: value}
 	case KING:
-		k := &King{color: color, value: 99.9}
-		if color == BLACK {
-			k.value *= float64(-1)
-		}
-		return k
+		return &King{color: color, value: value}
 	default:
 		return &Null{}
 	}
@@ -219,194 +228,103 @@ func (b *Board) resetCheck(color string) {
 }
 
 func (b *Board) setSquareGuards() {
-	blackAttackedSqs := b.GetAttackedSquares(WHITE)
-	whiteAttackedSqs := b.GetAttackedSquares(BLACK)
-	for _, row := range b.Squares {
-		for _, sq := range row {
-			if blackPieces, ok := blackAttackedSqs[sq]; ok {
-				sq.BlackGuards = blackPieces
-			} else {
-				sq.BlackGuards = []Piece{}
-			}
-			if whitePieces, ok := whiteAttackedSqs[sq]; ok {
-				sq.WhiteGuards = whitePieces
-			} else {
-				sq.WhiteGuards = []Piece{}
-			}
+	whiteGuards := b.guardsBySquare(b.WhitePieces)
+	blackGuards := b.guardsBySquare(b.BlackPieces)
+	for idx := 0; idx < 64; idx++ {
+		sq := b.squareAt(idx)
+		sq.WhiteGuards = whiteGuards[idx]
+		sq.BlackGuards = blackGuards[idx]
+	}
+}
+
+// guardsBySquare lists the pieces attacking each square. The lists share
+// one backing array so a whole side costs a single allocation.
+func (b *Board) guardsBySquare(pieces map[Piece]bool) [64][]Piece {
+	type pieceAttacks struct {
+		piece   Piece
+		attacks Bitboard
+	}
+	all := make([]pieceAttacks, 0, len(pieces))
+	counts := [64]int{}
+	total := 0
+	for piece := range pieces {
+		attacks := b.attacksOf(piece)
+		all = append(all, pieceAttacks{piece, attacks})
+		for bb := attacks; bb != 0; bb &= bb - 1 {
+			counts[bb.first()]++
 		}
+		total += attacks.Count()
 	}
-}
-
-func (b *Board) Fen() string {
-	fen := ""
 
-	for i, row := range b.Squares {
-		emptySqs := 0
-		for _, sq := range row {
-			if sq.Piece.Type() != NULL && emptySqs > 0 {
-				fen += strconv.Itoa(emptySqs)
-				emptySqs = 0
-			}
-
-			switch sq.Piece.Type() {
-			case NULL:
-				emptySqs += 1
-			case PAWN:
-				if sq.Piece.Color() == WHITE {
-					fen += "P"
-				} else {
-					fen += "p"
-				}
-			case KNIGHT:
-				if sq.Piece.Color() == WHITE {
-					fen += "N"
-				} else {
-					fen += "n"
-				}
-			case BISHOP:
-				if sq.Piece.Color() == WHITE {
-					fen += "B"
-				} else {
-					fen += "b"
-				}
-			case ROOK:
-				if sq.Piece.Color() == WHITE {
-					fen += "R"
-				} else {
-					fen += "r"
-				}
-			case QUEEN:
-				if sq.Piece.Color() == WHITE {
-					fen += "Q"
-				} else {
-					fen += "q"
-				}
-			case KING:
-				if sq.Piece.Color() == WHITE {
-					fen += "K"
-				} else {
-					fen += "k"
-				}
-			}
-		}
-		if emptySqs > 0 {
-			fen += strconv.Itoa(emptySqs)
-		}
-		if i < 7 {
-			fen += "/"
-		}
+	guards := [64][]Piece{}
+	backing := make([]Piece, total)
+	offset := 0
+	for idx, count := range counts {
+		guards[idx] = backing[offset : offset : offset+count]
+		offset += count
 	}
-	return fen
-}
-
-func (b *Board) SetupFromFen(fen string) {
-	fen = strings.ReplaceAll(fen, "/", "")
-	runes := []rune(fen)
-	idx := 0
-	runeDigits := map[rune]int{'1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '6': 6, '7': 7, '8': 8}
-	empties := 0
-	for _, row := range b.Squares {
-		for _, sq := range row {
-			if empties > 0 {
-				empties--
-			} else {
-				ch := runes[idx]
-				switch ch {
-				case 'p':
-					p := &Pawn{color: BLACK, value: -1.00}
-					if sq.Row != ROW_7 {
-						p.moveCount = 1
-					}
-					b.SetPiece(p, sq)
-				case 'P':
-					p := &Pawn{color: WHITE, value: 1.00}
-					if sq.Row != ROW_2 {
-						p.moveCount = 1
-					}
-					b.SetPiece(p, sq)
-				case 'n':
-					kn := &Knight{color: BLACK, value: -3.05}
-					b.SetPiece(kn, sq)
-				case 'N':
-					kn := &Knight{color: WHITE, value: 3.05}
-					b.SetPiece(kn, sq)
-				case 'b':
-					bish := &Bishop{color: BLACK, value: -3.33}
-					b.SetPiece(bish, sq)
-				case 'B':
-					bish := &Bishop{color: WHITE, value: 3.33}
-					b.SetPiece(bish, sq)
-				case 'r':
-					r := &Rook{color: BLACK, value: -5.63, moveCount: 1}
-					b.SetPiece(r, sq)
-				case 'R':
-					r := &Rook{color: WHITE, value: 5.63, moveCount: 1}
-					b.SetPiece(r, sq)
-				case 'q':
-					q := &Queen{color: BLACK, value: -9.5}
-					b.SetPiece(q, sq)
-				case 'Q':
-					q := &Queen{color: WHITE, value: 9.5}
-					b.SetPiece(q, sq)
-				case 'k':
-					k := &King{color: BLACK, value: -99.9}
-					b.SetPiece(k, sq)
-				case 'K':
-					k := &King{color: WHITE, value: 99.9}
-					b.SetPiece(k, sq)
-				default:
-					empties = runeDigits[ch] - 1
-				}
-				idx++
-			}
+	for _, pa := range all {
+		for bb := pa.attacks; bb != 0; bb &= bb - 1 {
+			idx := bb.first()
+			guards[idx] = append(guards[idx], pa.piece)
 		}
 	}
+	return guards
 }
 
+const (
+	DRAW_REPETITION            = "by repetition"
+	DRAW_FIVEFOLD_REPETITION   = "fivefold repetition"
+	DRAW_INSUFFICIENT_MATERIAL = "insufficient material"
+	DRAW_FIFTY_MOVES           = "fifty-move rule"
+	DRAW_SEVENTY_FIVE_MOVES    = "seventy-five-move rule"
+)
+
 func (b *Board) DrawDetected() bool {
-	if b.DrawByRepetition() {
-		return true
-	}
-	if b.DrawByInsufficientMaterial() {
-		return true
-	}
-	return false
+	return b.DrawReason() != ""
 }
 
-func (b *Board) DrawByRepetition() bool {
-	currFen := b.Fen()
-	if count, ok := b.Fens[currFen]; ok {
-		if count > 2 {
-			return true
-		}
+// DrawReason names the rule that has drawn the game, or returns an empty
+// string when the game goes on. Only the seventy-five-move rule, fivefold
+// repetition and insufficient material end the game by themselves; see
+// ClaimableDraw for the draws a player has to claim.
+func (b *Board) DrawReason() string {
+	switch {
+	case b.DrawBySeventyFiveMoves():
+		return DRAW_SEVENTY_FIVE_MOVES
+	case b.DrawByFivefoldRepetition():
+		return DRAW_FIVEFOLD_REPETITION
+	case b.DrawByInsufficientMaterial():
+		return DRAW_INSUFFICIENT_MATERIAL
+	default:
+		return ""
 	}
-	return false
 }
 
-func (b *Board) DrawByInsufficientMaterial() bool {
-	whiteValue := 0.0
-	whitePawns := 0
-	blackValue := 0.0
-	blackPawns := 0
-
-	for piece := range b.WhitePieces {
-		if piece.Type() == PAWN {
-			whitePawns++
-		}
-		whiteValue += piece.Value()
-	}
-	for piece := range b.BlackPieces {
-		if piece.Type() == PAWN {
-			blackPawns++
-		}
-		blackValue -= piece.Value()
+// ClaimableDraw names the rule under which the side to move may claim a
+// draw, the fifty-move rule or threefold repetition, or returns an empty
+// string when it may not. The game goes on until the draw is claimed.
+func (b *Board) ClaimableDraw() string {
+	switch {
+	case b.DrawByFiftyMoves():
+		return DRAW_FIFTY_MOVES
+	case b.DrawByRepetition():
+		return DRAW_REPETITION
+	default:
+		return ""
 	}
+}
 
-	if whiteValue < 104.0 && blackValue < 104.0 && whitePawns == 0 && blackPawns == 0 {
-		return true
-	}
+// DrawByFiftyMoves reports whether fifty moves by each side have been played
+// without a pawn move or a capture, so either side may claim a draw.
+func (b *Board) DrawByFiftyMoves() bool {
+	return b.HalfmoveClock >= 100
+}
 
-	return false
+// DrawBySeventyFiveMoves reports whether seventy-five moves by each side have
+// been played without a pawn move or a capture, which draws the game.
+func (b *Board) DrawBySeventyFiveMoves() bool {
+	return b.HalfmoveClock >= 150
 }
 
 func (b *Board) resetPins() {
@@ -420,53 +338,11 @@ func (b *Board) resetPins() {
 
 func (b *Board) CheckmateDetected(color string) bool {
 	king := b.GetKing(color)
-	if !king.Checked {
-		return false
-	}
-
-	kingActives := king.ActiveSquares()
-
-	switch {
-	case len(kingActives) == 0 && len(king.Checkers) > 1:
-		return true
-	case len(kingActives) > 0 && king.CanEvadeCheck(kingActives, b):
-		return false
-	default:
-		return !b.piecePreventsCheckmate(king)
-	}
+	return king.Checked && !b.hasLegalMove(color)
 }
 
 func (b *Board) StalemateDetected(color string) bool {
-	allies := b.getAllies(color)
-	for ally := range allies {
-		for _, activity := range ally.ActiveSquares() {
-			if activity != GUARDED {
-				return false
-			}
-		}
-	}
-	return true
-}
-
-func (b *Board) piecePreventsCheckmate(king *King) bool {
-	checker := king.Checkers[0]
-	checkerSq := checker.Square()
-	checkPath := b.GetAttackedPath(checkerSq, king.Square())
-	allies := b.getAllies(king.Color())
-	for piece := range allies {
-		if piece.Type() == KING {
-			continue
-		}
-		pieceActives := piece.ActiveSquares()
-		for sq := range checkPath {
-			if moveType, ok := pieceActives[sq]; ok {
-				if moveType == FREE || moveType == CAPTURE {
-					return true
-				}
-			}
-		}
-	}
-	return false
+	return !b.hasLegalMove(color)
 }
 
 func (b *Board) getAllies(color string) map[Piece]bool {
@@ -485,57 +361,71 @@ func (b *Board) getEnemies(color string) map[Piece]bool {
 	}
 }
 
+// GetAttackedSquares returns the squares attacked by the enemies of color
+// along with the pieces attacking each one.
 func (b *Board) GetAttackedSquares(color string) map[*Square][]Piece {
-	enemies := b.getEnemies(color)
-
 	attackedSqs := make(map[*Square][]Piece)
-
-	for piece := range enemies {
-		if king, ok := piece.(*King); ok {
-			b.addKingAttackedSquares(king, attackedSqs)
-			continue
-		} else {
-			pieceActives := piece.ActiveSquares()
-			for sq, sqActivity := range pieceActives {
-				if _, ok := attackedSqs[sq]; !ok {
-					if piece.Type() == PAWN && sqActivity == FREE {
-						continue
-					}
-				}
-				addAttackedSquare(sq, piece, attackedSqs)
-			}
+	for piece := range b.getEnemies(color) {
+		for bb := b.attacksOf(piece); bb != 0; bb &= bb - 1 {
+			sq := b.squareAt(bb.first())
+			attackedSqs[sq] = append(attackedSqs[sq], piece)
 		}
 	}
 	return attackedSqs
 }
 
-func (b *Board) addKingAttackedSquares(king *King, attackedSqs map[*Square][]Piece) {
-	for _, dir := range KING_DIRS {
-		row := king.Square().Row + dir[0]
-		col := king.Square().Column + dir[1]
-
-		if cand, ok := b.GetSquareIfExists(row, col); ok {
-			addAttackedSquare(cand, king, attackedSqs)
+// attacksOf returns the squares piece attacks, including the square behind
+// an enemy king that it checks along a line.
+func (b *Board) attacksOf(piece Piece) Bitboard {
+	idx := squareIndex(piece.Square())
+	color := colorIndex(piece.Color())
+	kind := kindIndex(piece.Type())
+	attacks := pieceAttacks(kind, color, idx, b.bb.all)
+	return attacks | b.kingXRay(kind, idx, color, attacks)
+}
+
+// attackers returns the pieces of color that attack sq, found from the piece
+// geometry alone rather than from the cached active squares.
+func (b *Board) attackers(sq *Square, color string) []Piece {
+	found := []Piece{}
+	addIf := func(row, col int, types ...string) {
+		cand, ok := b.GetSquareIfExists(row, col)
+		if !ok || !cand.Piece.IsAlly(color) {
+			return
+		}
+		for _, pieceType := range types {
+			if cand.Piece.Type() == pieceType {
+				found = append(found, cand.Piece)
+				return
+			}
 		}
 	}
-}
 
-func (b *Board) checkSquarePastKing(row, col int, coords [2]int) (*Square, bool) {
-	candRow := row + coords[0]
-	candCol := col + coords[1]
-	if cand, ok := b.GetSquareIfExists(candRow, candCol); ok {
-		return cand, true
+	for _, dir := range KNIGHT_DIRS {
+		addIf(sq.Row+dir[0], sq.Column+dir[1], KNIGHT)
 	}
-	return nil, false
-}
+	for _, dir := range KING_DIRS {
+		addIf(sq.Row+dir[0], sq.Column+dir[1], KING)
+	}
+	pawnRow := sq.Row + 1
+	if color == BLACK {
+		pawnRow = sq.Row - 1
+	}
+	addIf(pawnRow, sq.Column-1, PAWN)
+	addIf(pawnRow, sq.Column+1, PAWN)
 
-func addAttackedSquare(sq *Square, piece Piece, attackeds map[*Square][]Piece) {
-	_, ok := attackeds[sq]
-	if ok {
-		attackeds[sq] = append(attackeds[sq], piece)
-	} else {
-		attackeds[sq] = []Piece{piece}
+	for _, dir := range KING_DIRS {
+		sliders := []string{ROOK, QUEEN}
+		if dir[0] != 0 && dir[1] != 0 {
+			sliders = []string{BISHOP, QUEEN}
+		}
+		row, col := sq.Row+dir[0], sq.Column+dir[1]
+		for squareExists(row, col) && b.Squares[row][col].IsEmpty() {
+			row, col = row+dir[0], col+dir[1]
+		}
+		addIf(row, col, sliders...)
 	}
+	return found
 }
 
 func (b *Board) GetAttackedPath(from, to *Square) map[*Square]bool {
@@ -708,6 +598,7 @@ func (b *Board) SetupPieces() {
 			}
 		}
 	}
+	b.hash = b.computeHash()
 	b.Evaluate(WHITE)
 }
 
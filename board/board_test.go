@@ -1,6 +1,8 @@
 package board
 
 import (
+	"errors"
+	"math"
 	"testing"
 )
 
@@ -37,7 +39,7 @@ func TestUndoMove(t *testing.T) {
 
 	//EN_PASSANT
 	board5 := New()
-	board5.SetupFromFen("rnbqkbnr/pp1ppppp/2p5/4P3/8/8/PPPP1PPP/RNBQKBNR")
+	board5.SetupFromFen("rnbqkbnr/pp1ppppp/2p5/4P3/8/8/PPPP1PPP/RNBQKBNR b - - 0 1")
 	e5_5 := board5.GetSquare(ROW_5, COL_E)
 	d6_5 := board5.GetSquare(ROW_6, COL_D)
 	d7_5 := board5.GetSquare(ROW_7, COL_D)
@@ -65,7 +67,7 @@ func TestUndoMove(t *testing.T) {
 				From:  e2_1,
 				To:    e4_1,
 			},
-			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
 		},
 		{
 			board2,
@@ -75,7 +77,7 @@ func TestUndoMove(t *testing.T) {
 				From:  e7_2,
 				To:    e8_2,
 			},
-			"8/pp2P3/8/3k2p1/8/2P3P1/P5P1/5K2",
+			"8/pp2P3/8/3k2p1/8/2P3P1/P5P1/5K2 w - - 0 1",
 		},
 		{
 			board3,
@@ -85,7 +87,7 @@ func TestUndoMove(t *testing.T) {
 				From:  e4_3,
 				To:    d5_3,
 			},
-			"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR",
+			"rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w - - 0 1",
 		},
 		{
 			board4,
@@ -95,7 +97,7 @@ func TestUndoMove(t *testing.T) {
 				From:  e1_4,
 				To:    g1_4,
 			},
-			"rnbqkbnr/pp3ppp/2p1p3/3p4/4P3/5N2/PPPPBPPP/RNBQK2R",
+			"rnbqkbnr/pp3ppp/2p1p3/3p4/4P3/5N2/PPPPBPPP/RNBQK2R w K - 0 1",
 		},
 		{
 			board5,
@@ -105,7 +107,7 @@ func TestUndoMove(t *testing.T) {
 				From:  e5_5,
 				To:    d6_5,
 			},
-			"rnbqkbnr/pp2pppp/2p5/3pP3/8/8/PPPP1PPP/RNBQKBNR",
+			"rnbqkbnr/pp2pppp/2p5/3pP3/8/8/PPPP1PPP/RNBQKBNR w - d6 0 2",
 		},
 	}
 	for _, tt := range tests {
@@ -136,8 +138,8 @@ func TestUndoMove(t *testing.T) {
 		if len(ogBoard.Moves) != len(tt.board.Moves) {
 			t.Fatalf("len board.Moves should be %d. Got %d", len(ogBoard.Moves), len(tt.board.Moves))
 		}
-		if len(ogBoard.Fens) != len(tt.board.Fens) {
-			t.Fatalf("len Fens should be %d. Got %d", len(ogBoard.Fens), len(tt.board.Fens))
+		if len(ogBoard.Hashes) != len(tt.board.Hashes) {
+			t.Fatalf("len Hashes should be %d. Got %d", len(ogBoard.Hashes), len(tt.board.Hashes))
 		}
 		if len(tt.board.WhitePieces) != lenOGWhitePieces {
 			t.Fatalf("len WhitePieces should be %d. Got %d", lenOGWhitePieces, len(tt.board.WhitePieces))
@@ -150,41 +152,210 @@ func TestUndoMove(t *testing.T) {
 }
 
 func TestSetupFromFen(t *testing.T) {
-	board1 := New()
-	board2 := New()
-	board3 := New()
-
 	tests := []struct {
-		board    *Board
 		input    string
 		expected string
 	}{
 		{
-			board1,
-			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR",
-			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
 		},
 		{
-			board2,
-			"r1b1k1nr/ppp2ppp/2n5/3Pp3/1b6/P1P2N2/1P1qQPPP/RNB1KB1R",
-			"r1b1k1nr/ppp2ppp/2n5/3Pp3/1b6/P1P2N2/1P1qQPPP/RNB1KB1R",
+			"r1b1k1nr/ppp2ppp/2n5/3Pp3/1b6/P1P2N2/1P1qQPPP/RNB1KB1R w KQkq e6 0 9",
+			"r1b1k1nr/ppp2ppp/2n5/3Pp3/1b6/P1P2N2/1P1qQPPP/RNB1KB1R w KQkq e6 0 9",
 		},
 		{
-			board3,
-			"r3k1nr/pp3pQp/2p5/3Pp3/1P6/P1N2N1b/5PPP/n1BK1B1R",
-			"r3k1nr/pp3pQp/2p5/3Pp3/1P6/P1N2N1b/5PPP/n1BK1B1R",
+			"r3k1nr/pp3pQp/2p5/3Pp3/1P6/P1N2N1b/5PPP/n1BK1B1R b kq - 3 17",
+			"r3k1nr/pp3pQp/2p5/3Pp3/1P6/P1N2N1b/5PPP/n1BK1B1R b kq - 3 17",
+		},
+		{
+			"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 12 40",
+			"r3k2r/8/8/8/8/8/8/R3K2R b Kq - 12 40",
+		},
+		{
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3",
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		},
+		{
+			"r1b1k1nr/ppp2ppp/2n5/3Pp3/1b6/P1P2N2/1P1qQPPP/RNB1KB1R",
+			"r1b1k1nr/ppp2ppp/2n5/3Pp3/1b6/P1P2N2/1P1qQPPP/RNB1KB1R w - - 0 1",
 		},
 	}
 
 	for _, tt := range tests {
-		tt.board.SetupFromFen(tt.input)
-		fen := tt.board.Fen()
+		board := New()
+		if err := board.SetupFromFen(tt.input); err != nil {
+			t.Fatalf("SetupFromFen(%q) returned error: %s", tt.input, err.Message)
+		}
+		fen := board.Fen()
 		if fen != tt.expected {
 			t.Fatalf("Fen should be %s. Got %s", tt.expected, fen)
 		}
 	}
 }
 
+func TestSetupFromFenCastlingRights(t *testing.T) {
+	board := New()
+	board.SetupFromFen("r3k2r/8/8/8/8/8/8/R3K2R w Kq - 0 1")
+	board.Evaluate(BLACK)
+
+	tests := []struct {
+		king     *King
+		castleSq *Square
+		expected bool
+	}{
+		{board.GetKing(WHITE), board.Squares[ROW_1][COL_G], true},
+		{board.GetKing(WHITE), board.Squares[ROW_1][COL_C], false},
+		{board.GetKing(BLACK), board.Squares[ROW_8][COL_G], false},
+		{board.GetKing(BLACK), board.Squares[ROW_8][COL_C], true},
+	}
+
+	for _, tt := range tests {
+		tt.king.SetActiveSquares(board)
+		activity, ok := tt.king.ActiveSquares()[tt.castleSq]
+		canCastle := ok && activity == CASTLE
+		if canCastle != tt.expected {
+			t.Fatalf("%s king castling to %s should be %t. Got %t", tt.king.Color(), tt.castleSq.Name, tt.expected, canCastle)
+		}
+	}
+}
+
+func TestSetupFromFenEnPassant(t *testing.T) {
+	board := New()
+	board.SetupFromFen("4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 30")
+	board.Evaluate(BLACK)
+
+	e5 := board.Squares[ROW_5][COL_E]
+	d6 := board.Squares[ROW_6][COL_D]
+	move := &Move{Turn: WHITE, Piece: e5.Piece, From: e5, To: d6}
+	receipt, err := board.MovePiece(move)
+	if err != nil {
+		t.Fatalf("en passant from fen should be valid. Got %s", receipt)
+	}
+	expected := "4k3/8/3P4/8/8/8/8/4K3 b - - 0 30"
+	if fen := board.Fen(); fen != expected {
+		t.Fatalf("Fen should be %s. Got %s", expected, fen)
+	}
+	board.UndoMove()
+	expected = "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 30"
+	if fen := board.Fen(); fen != expected {
+		t.Fatalf("Fen should be %s. Got %s", expected, fen)
+	}
+}
+
+func TestFenAfterMoves(t *testing.T) {
+	board := New()
+	board.SetupPieces()
+
+	moves := [][4]int{
+		{ROW_2, COL_E, ROW_4, COL_E},
+		{ROW_8, COL_G, ROW_6, COL_F},
+		{ROW_1, COL_E, ROW_2, COL_E},
+	}
+	expected := []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"rnbqkb1r/pppppppp/5n2/8/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 1 2",
+		"rnbqkb1r/pppppppp/5n2/8/4P3/8/PPPPKPPP/RNBQ1BNR b kq - 2 2",
+	}
+	for i, coords := range moves {
+		from := board.Squares[coords[0]][coords[1]]
+		to := board.Squares[coords[2]][coords[3]]
+		move := &Move{Turn: board.Turn, Piece: from.Piece, From: from, To: to}
+		if receipt, err := board.MovePiece(move); err != nil {
+			t.Fatalf("move %d should be valid. Got %s", i+1, receipt)
+		}
+		if fen := board.Fen(); fen != expected[i] {
+			t.Fatalf("Fen should be %s. Got %s", expected[i], fen)
+		}
+	}
+}
+
+func TestMoveOutOfTurn(t *testing.T) {
+	board := New()
+	board.SetupPieces()
+	board.Evaluate(BLACK)
+	fen := board.Fen()
+
+	from, to := board.Squares[ROW_7][COL_E], board.Squares[ROW_5][COL_E]
+	move := &Move{Turn: BLACK, Piece: from.Piece, From: from, To: to}
+	receipt, err := board.MovePiece(move)
+	if err == nil || !errors.Is(err, ErrWrongTurn) {
+		t.Fatalf("a black move on white's turn should be rejected. Got %s", receipt)
+	}
+	if receipt != "It is WHITE's turn to move" {
+		t.Fatalf("receipt should be 'It is WHITE's turn to move'. Got '%s'", receipt)
+	}
+	if board.Fen() != fen {
+		t.Fatalf("Fen should stay %s. Got %s", fen, board.Fen())
+	}
+
+	from, to = board.Squares[ROW_3][COL_E], board.Squares[ROW_4][COL_E]
+	move = &Move{Turn: WHITE, Piece: from.Piece, From: from, To: to}
+	receipt, err = board.MovePiece(move)
+	if err == nil || errors.Is(err, ErrWrongTurn) {
+		t.Fatalf("a move from an empty square should be an invalid move. Got %s", receipt)
+	}
+	if receipt != "NULL: E3 -> E4 is not a valid move" {
+		t.Fatalf("receipt should be 'NULL: E3 -> E4 is not a valid move'. Got '%s'", receipt)
+	}
+}
+
+func TestCheckMove(t *testing.T) {
+	board := New()
+	board.SetupPieces()
+	board.Evaluate(BLACK)
+	fen := board.Fen()
+
+	from := board.Squares[ROW_2][COL_E]
+	tests := []struct {
+		move     *Move
+		expected string
+	}{
+		{&Move{Turn: WHITE, Piece: from.Piece, From: from, To: board.Squares[ROW_4][COL_E]}, ""},
+		{&Move{Turn: WHITE, Piece: from.Piece, From: from, To: board.Squares[ROW_5][COL_E]}, "PAWN: E2 -> E5 is not a valid move"},
+	}
+	for _, tt := range tests {
+		receipt, err := board.CheckMove(tt.move)
+		if receipt != tt.expected || (err == nil) != (tt.expected == "") {
+			t.Fatalf("receipt should be '%s'. Got '%s'", tt.expected, receipt)
+		}
+		if board.Fen() != fen {
+			t.Fatalf("CheckMove should leave the board at %s. Got %s", fen, board.Fen())
+		}
+	}
+}
+
+func TestSetupFromFenErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR/8 w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/9/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/ppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNRR w KQkq - 0 1",
+		"rnbqkbnr/ppppxppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQxq - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w QK - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq z3 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - -1 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 extra",
+	}
+
+	for _, input := range tests {
+		board := New()
+		if err := board.SetupFromFen(input); err == nil {
+			t.Fatalf("SetupFromFen(%q) should return an error", input)
+		}
+		if fen := board.PlacementFen(); fen != "8/8/8/8/8/8/8/8" {
+			t.Fatalf("board should be untouched after %q. Got %s", input, fen)
+		}
+	}
+}
+
 func TestBoardCopy(t *testing.T) {
 	board1 := New()
 	board1.SetupPieces()
@@ -223,8 +394,8 @@ func TestBoardCopy(t *testing.T) {
 		board    *Board
 		expected string
 	}{
-		{board1, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"},
-		{board2, "1b6/4K3/8/1PR5/1B3k1p/8/8/8"},
+		{board1, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{board2, "1b6/4K3/8/1PR5/1B3k1p/8/8/8 w - - 0 1"},
 	}
 
 	for _, tt := range tests {
@@ -236,6 +407,28 @@ func TestBoardCopy(t *testing.T) {
 	}
 }
 
+func TestBoardCopyEvaluation(t *testing.T) {
+	board := New()
+	board.SetupFromFen("r3k2r/pppq1ppp/2npbn2/2b1p3/2B1P3/2NPBN2/PPPQ1PPP/R3K2R w KQkq - 0 1")
+	board.Evaluate(BLACK)
+	before := board.Value
+
+	playSans(t, board, "O-O")
+	copy := board.Copy()
+	copy.Evaluate(WHITE)
+	if math.Abs(copy.Value-board.Value) > 1e-9 {
+		t.Fatalf("copy evaluates to %f, board to %f", copy.Value, board.Value)
+	}
+
+	board.UndoMove()
+	if board.GetKing(WHITE).Castled {
+		t.Fatalf("king still castled after undoing O-O")
+	}
+	if math.Abs(board.Value-before) > 1e-9 {
+		t.Fatalf("expected value %f after undoing O-O, got %f", before, board.Value)
+	}
+}
+
 func TestFen(t *testing.T) {
 	board1 := New()
 	board1.SetupPieces()
@@ -274,8 +467,8 @@ func TestFen(t *testing.T) {
 		board    *Board
 		expected string
 	}{
-		{board1, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"},
-		{board2, "1b6/4K3/8/1PR5/1B3k1p/8/8/8"},
+		{board1, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{board2, "1b6/4K3/8/1PR5/1B3k1p/8/8/8 w - - 0 1"},
 	}
 	for _, tt := range tests {
 		fen := tt.board.Fen()
@@ -415,3 +608,56 @@ func TestSetupPieces(t *testing.T) {
 		}
 	}
 }
+
+func TestDrawByFiftyMoves(t *testing.T) {
+	tests := []struct {
+		fen            string
+		san            string
+		expectedClock  int
+		expectedDraw   bool
+		expectedReason string
+		expectedClaim  string
+	}{
+		{"4k3/8/8/8/8/8/4P3/R3K3 w - - 98 80", "Ra2", 99, false, "", ""},
+		{"4k3/8/8/8/8/8/4P3/R3K3 w - - 99 80", "Ra2", 100, false, "", DRAW_FIFTY_MOVES},
+		{"4k3/8/8/8/8/8/4P3/R3K3 w - - 99 80", "e3", 0, false, "", ""},
+		{"4k3/8/8/8/8/8/4P3/R3K3 w - - 149 80", "Rb1", 150, true, DRAW_SEVENTY_FIVE_MOVES, DRAW_FIFTY_MOVES},
+		{"4k3/8/8/8/8/8/r3P3/R3K3 w - - 99 80", "Rxa2", 0, false, "", ""},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		board.Evaluate(BLACK)
+		move, err := board.ParseSan(tt.san)
+		if err != nil {
+			t.Fatalf("%s: ParseSan(%q) returned error: %s", tt.fen, tt.san, err.Message)
+		}
+		board.MovePiece(move)
+		if board.HalfmoveClock != tt.expectedClock {
+			t.Fatalf("%s %s: halfmove clock should be %d. Got %d", tt.fen, tt.san, tt.expectedClock, board.HalfmoveClock)
+		}
+		if board.Draw != tt.expectedDraw || board.DrawReason() != tt.expectedReason {
+			t.Fatalf("%s %s: draw should be %t (%q). Got %t (%q)", tt.fen, tt.san, tt.expectedDraw, tt.expectedReason, board.Draw, board.DrawReason())
+		}
+		if claim := board.ClaimableDraw(); claim != tt.expectedClaim {
+			t.Fatalf("%s %s: claimable draw should be %q. Got %q", tt.fen, tt.san, tt.expectedClaim, claim)
+		}
+
+		board.UndoMove()
+		if board.Fen() != tt.fen || board.Draw != (board.HalfmoveClock >= 150) {
+			t.Fatalf("undo should restore %s. Got %s, draw %t", tt.fen, board.Fen(), board.Draw)
+		}
+	}
+}
+
+func TestCheckmateBeatsFiftyMoveRule(t *testing.T) {
+	board := New()
+	board.SetupFromFen("6k1/5ppp/8/8/8/8/8/R5K1 w - - 99 80")
+	board.Evaluate(BLACK)
+	move, _ := board.ParseSan("Ra8")
+	board.MovePiece(move)
+	if !board.Checkmate || board.Draw {
+		t.Fatalf("mate on the hundredth halfmove should stand. Got checkmate %t, draw %t", board.Checkmate, board.Draw)
+	}
+}
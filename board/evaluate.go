@@ -1,7 +1,7 @@
 package board
 
 import (
-	"fmt"
+	"context"
 	"math"
 )
 
@@ -9,32 +9,22 @@ func (b *Board) Evaluate(turn string) {
 	b.Value = 0.0
 	b.resetCheck(turn)
 	b.resetPins()
-	if turn == WHITE {
-		b.evaluateWhite()
-		blackKing := b.GetKing(BLACK)
-		blackKing.SetCheck(b)
-		b.evaluateBlack()
-		if b.CheckmateDetected(BLACK) {
-			b.Checkmate = true
-			return
-		}
-		if b.StalemateDetected(BLACK) {
-			b.Stalemate = true
-			return
-		}
-	} else {
-		b.evaluateBlack()
-		whiteKing := b.GetKing(WHITE)
-		whiteKing.SetCheck(b)
-		b.evaluateWhite()
-		if b.CheckmateDetected(WHITE) {
-			b.Checkmate = true
-			return
-		}
-		if b.StalemateDetected(WHITE) {
-			b.Stalemate = true
-			return
-		}
+	b.syncBitboards()
+	enemy := ENEMY[turn]
+	b.GetKing(enemy).SetCheck(b)
+	b.setPins(WHITE)
+	b.setPins(BLACK)
+	b.evaluateWhite()
+	b.evaluateBlack()
+	b.Value += b.pieceSquareScore()
+	b.Value += b.pawnStructureScore()
+	if b.CheckmateDetected(enemy) {
+		b.Checkmate = true
+		return
+	}
+	if b.StalemateDetected(enemy) {
+		b.Stalemate = true
+		return
 	}
 	b.setSquareGuards()
 	if b.DrawDetected() {
@@ -75,9 +65,12 @@ func (b *Board) evaluateBlack() {
 	}
 }
 
+// BestMove searches four plies deep for the best move of turn.
 func (b *Board) BestMove(turn string) *Move {
-	move, _ := b.MiniMax(turn, math.Inf(-1), math.Inf(1), 4)
-	return move
+	if b.TT == nil {
+		b.TT = NewTranspositionTable(DEFAULT_TT_MB)
+	}
+	return b.Search(context.Background(), turn, SearchLimits{Depth: 4}).Move
 }
 
 func (b *Board) SimPosition(move *Move) *Board {
@@ -91,42 +84,83 @@ func (b *Board) SimPosition(move *Move) *Board {
 }
 
 func (b *Board) MiniMax(turn string, alpha float64, beta float64, depth int) (*Move, float64) {
+	ply := b.search.ply(b)
+	b.search.clearPV(ply)
 	if depth == 0 {
-		return nil, b.Value
+		return nil, b.Quiescence(turn, alpha, beta)
+	}
+	if b.search.visit() {
+		return nil, 0.0
 	}
 	if b.Checkmate {
-		fmt.Println("CHECKMATE DETECTED")
-		if turn == WHITE {
-			return nil, -99.9
-		} else {
-			return nil, 99.9
-		}
+		return nil, mateScore(turn, ply)
 	}
 	if b.Stalemate || b.Draw {
-		fmt.Println("DRAW DETECTED")
 		return nil, 0.0
 	}
+	if ply > 0 && b.ClaimableDraw() != "" {
+		// scored as drawn, since a side doing worse would claim it
+		return nil, 0.0
+	}
+	if ply > 0 {
+		// Mate distance pruning: nothing from here scores better than
+		// mating at once or worse than being mated now, so a window
+		// outside those scores is already settled.
+		if best := mateScore(BLACK, ply); alpha >= best {
+			return nil, best
+		}
+		if worst := mateScore(WHITE, ply); beta <= worst {
+			return nil, worst
+		}
+	}
+
+	key := b.hash
+	alphaOrig, betaOrig := alpha, beta
+	entry, found := b.TT.probe(key)
+	if found {
+		entry.score = scoreFromTT(entry.score, ply)
+	}
+	if found && !b.search.excluding(ply) && b.TT.ttCutoff(entry, depth, alpha, beta) {
+		if move := b.ttMoveFor(entry.move); move != nil {
+			b.search.clearPV(ply + 1)
+			b.search.updatePV(ply, move)
+			return move, entry.score
+		}
+	}
+	if score, cutoff := b.tryNullMove(turn, alpha, beta, depth, ply); cutoff {
+		return nil, score
+	}
+	inCheck := b.inCheck(turn)
 
 	if turn == WHITE {
 		maxEval := math.Inf(-1)
 		maxMove := &Move{}
 
 		valids := b.GetAllValidMoves(turn)
+		b.orderMoves(valids, entry.move, ply)
 
-		for _, move := range valids {
-			b.MovePiece(move)
-			_, eval := b.MiniMax(BLACK, alpha, beta, depth-1)
-			//fmt.Println("DEPTH: ", depth)
-			b.UndoMove()
+		for i, move := range valids {
+			if b.search.excludedAt(ply, move) {
+				continue
+			}
+			eval := b.searchMove(turn, move, alpha, beta, depth, ply, i, inCheck)
+			if b.search.aborted() {
+				return nil, 0.0
+			}
 			if eval > maxEval {
 				maxEval = eval
 				maxMove = move
+				b.search.updatePV(ply, move)
 			}
 			alpha = math.Max(alpha, eval)
 			if beta < alpha {
+				b.search.recordCutoff(move, ply, depth)
 				break
 			}
 		}
+		if !b.search.excluding(ply) {
+			b.TT.store(key, depth, scoreToTT(maxEval, ply), scoreBound(maxEval, alphaOrig, betaOrig), maxMove)
+		}
 		return maxMove, maxEval
 
 	} else {
@@ -134,21 +168,31 @@ func (b *Board) MiniMax(turn string, alpha float64, beta float64, depth int) (*M
 		minMove := &Move{}
 
 		valids := b.GetAllValidMoves(turn)
+		b.orderMoves(valids, entry.move, ply)
 
-		for _, move := range valids {
-			b.MovePiece(move)
-			_, eval := b.MiniMax(WHITE, alpha, beta, depth-1)
-			b.UndoMove()
+		for i, move := range valids {
+			if b.search.excludedAt(ply, move) {
+				continue
+			}
+			eval := b.searchMove(turn, move, alpha, beta, depth, ply, i, inCheck)
+			if b.search.aborted() {
+				return nil, 0.0
+			}
 			if eval < minEval {
 				minEval = eval
 				minMove = move
+				b.search.updatePV(ply, move)
 			}
 			beta = math.Min(beta, eval)
 			if beta < alpha {
+				b.search.recordCutoff(move, ply, depth)
 				break
 			}
 		}
 
+		if !b.search.excluding(ply) {
+			b.TT.store(key, depth, scoreToTT(minEval, ply), scoreBound(minEval, alphaOrig, betaOrig), minMove)
+		}
 		return minMove, minEval
 	}
 }
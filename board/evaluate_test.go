@@ -8,11 +8,11 @@ import (
 func TestMiniMax(t *testing.T) {
 	testNum := 1
 	brd1 := New()
-	brd1.SetupFromFen("2bqk3/5p2/8/1Q6/8/8/3P4/4K3")
+	brd1.SetupFromFen("2bqk3/5p2/8/1Q6/8/8/3P4/4K3 b - - 0 1")
 	brd1.Evaluate(BLACK)
 
 	brd2 := New()
-	brd2.SetupFromFen("rn3rk1/pp2bppp/2p2p1B/8/2Pq4/1P1B2QP/P4PPK/8")
+	brd2.SetupFromFen("rn3rk1/pp2bppp/2p2p1B/8/2Pq4/1P1B2QP/P4PPK/8 b - - 0 1")
 	brd2.Evaluate(WHITE)
 
 	tests := []struct {
@@ -0,0 +1,372 @@
+package board
+
+import (
+	"strconv"
+	"strings"
+)
+
+var fenSymbols = map[string]string{
+	PAWN:   "p",
+	KNIGHT: "n",
+	BISHOP: "b",
+	ROOK:   "r",
+	QUEEN:  "q",
+	KING:   "k",
+}
+
+var fenPieces = map[rune]string{
+	'p': PAWN,
+	'n': KNIGHT,
+	'b': BISHOP,
+	'r': ROOK,
+	'q': QUEEN,
+	'k': KING,
+}
+
+type fenPosition struct {
+	placement      [8][8]rune
+	turn           string
+	castling       string
+	enPassant      [2]int
+	hasEnPassant   bool
+	halfmoveClock  int
+	fullmoveNumber int
+}
+
+// Fen returns the position as a standard six-field FEN string.
+func (b *Board) Fen() string {
+	fields := []string{
+		b.PlacementFen(),
+		b.turnFen(),
+		b.castlingFen(),
+		b.enPassantFen(),
+		strconv.Itoa(b.HalfmoveClock),
+		strconv.Itoa(b.FullmoveNumber),
+	}
+	return strings.Join(fields, " ")
+}
+
+// PlacementFen returns only the piece placement field of the FEN.
+func (b *Board) PlacementFen() string {
+	fen := make([]byte, 0, 71)
+	for i, row := range b.Squares {
+		emptySqs := 0
+		for _, sq := range row {
+			if sq.IsEmpty() {
+				emptySqs++
+				continue
+			}
+			if emptySqs > 0 {
+				fen = append(fen, byte('0'+emptySqs))
+				emptySqs = 0
+			}
+			symbol := fenSymbols[sq.Piece.Type()][0]
+			if sq.Piece.Color() == WHITE {
+				symbol -= 'a' - 'A'
+			}
+			fen = append(fen, symbol)
+		}
+		if emptySqs > 0 {
+			fen = append(fen, byte('0'+emptySqs))
+		}
+		if i < 7 {
+			fen = append(fen, '/')
+		}
+	}
+	return string(fen)
+}
+
+func (b *Board) turnFen() string {
+	if b.Turn == BLACK {
+		return "b"
+	}
+	return "w"
+}
+
+func (b *Board) castlingFen() string {
+	rights := ""
+	if b.castleRightAvailable(WHITE, COL_H) {
+		rights += "K"
+	}
+	if b.castleRightAvailable(WHITE, COL_A) {
+		rights += "Q"
+	}
+	if b.castleRightAvailable(BLACK, COL_H) {
+		rights += "k"
+	}
+	if b.castleRightAvailable(BLACK, COL_A) {
+		rights += "q"
+	}
+	if rights == "" {
+		return "-"
+	}
+	return rights
+}
+
+func (b *Board) enPassantFen() string {
+	if b.EnPassant == nil {
+		return "-"
+	}
+	return strings.ToLower(b.EnPassant.Name)
+}
+
+// castleRightAvailable reports whether color still has the right to castle
+// with the rook that started on rookCol. Rights live in the move counts of
+// the king and rook, so they are lost as soon as either piece moves.
+func (b *Board) castleRightAvailable(color string, rookCol int) bool {
+	row := ROW_1
+	if color == BLACK {
+		row = ROW_8
+	}
+	king, ok := b.Squares[row][COL_E].Piece.(*King)
+	if !ok || king.Color() != color || king.HasMoved() {
+		return false
+	}
+	rook, ok := b.Squares[row][rookCol].Piece.(*Rook)
+	if !ok || rook.Color() != color || rook.HasMoved() {
+		return false
+	}
+	return true
+}
+
+// SetupFromFen places the pieces and game state described by fen on the
+// board. A full six-field FEN is expected, but the clocks may be omitted and
+// a bare piece placement is accepted as white to move without castling
+// rights. The board is left untouched if fen is malformed.
+func (b *Board) SetupFromFen(fen string) *Error {
+	pos, errs := parseFen(fen)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	b.setupFromFenPosition(pos)
+	return nil
+}
+
+func (b *Board) setupFromFenPosition(pos *fenPosition) {
+	b.clear()
+	for i, row := range b.Squares {
+		for j, sq := range row {
+			ch := pos.placement[i][j]
+			if ch == 0 {
+				continue
+			}
+			color := BLACK
+			if ch >= 'A' && ch <= 'Z' {
+				color = WHITE
+			}
+			piece := b.CreatePiece(color, fenPieces[toLowerRune(ch)])
+			b.SetPiece(piece, sq)
+		}
+	}
+	b.setMoveCountsFromFen(pos.castling)
+
+	b.Turn = pos.turn
+	if pos.hasEnPassant {
+		b.EnPassant = b.Squares[pos.enPassant[0]][pos.enPassant[1]]
+	}
+	b.HalfmoveClock = pos.halfmoveClock
+	b.FullmoveNumber = pos.fullmoveNumber
+	b.hash = b.computeHash()
+}
+
+// setMoveCountsFromFen marks pawns off their starting rank as moved and
+// gives kings and rooks the move counts that reproduce the castling field.
+func (b *Board) setMoveCountsFromFen(castling string) {
+	castleRooks := map[rune][2]int{
+		'K': {ROW_1, COL_H},
+		'Q': {ROW_1, COL_A},
+		'k': {ROW_8, COL_H},
+		'q': {ROW_8, COL_A},
+	}
+	unmoved := map[*Square]bool{}
+	for _, right := range castling {
+		coords, ok := castleRooks[right]
+		if !ok {
+			continue
+		}
+		unmoved[b.Squares[coords[0]][coords[1]]] = true
+		unmoved[b.Squares[coords[0]][COL_E]] = true
+	}
+
+	for _, row := range b.Squares {
+		for _, sq := range row {
+			piece := sq.Piece
+			switch piece.Type() {
+			case PAWN:
+				startRow := ROW_2
+				if piece.Color() == BLACK {
+					startRow = ROW_7
+				}
+				if sq.Row != startRow {
+					piece.SetMoveCount(1)
+				}
+			case ROOK, KING:
+				if !unmoved[sq] {
+					piece.SetMoveCount(1)
+				}
+			}
+		}
+	}
+}
+
+func (b *Board) clear() {
+	for _, row := range b.Squares {
+		for _, sq := range row {
+			sq.SetPiece(&Null{})
+			sq.WhiteGuards = []Piece{}
+			sq.BlackGuards = []Piece{}
+		}
+	}
+	b.WhitePieces = make(map[Piece]bool)
+	b.BlackPieces = make(map[Piece]bool)
+	b.Moves = []*Move{}
+	b.PromotedPawns = []*Pawn{}
+	b.CapturedPieces = []Piece{}
+	b.Receipts = []string{}
+	b.Hashes = []uint64{}
+	b.Checkmate = false
+	b.Stalemate = false
+	b.Draw = false
+	b.Value = 0.0
+	b.Turn = WHITE
+	b.EnPassant = nil
+	b.HalfmoveClock = 0
+	b.FullmoveNumber = 1
+	b.hash = 0
+}
+
+func parseFen(fen string) (*fenPosition, []*Error) {
+	fields := strings.Fields(fen)
+	pos := &fenPosition{
+		turn:           WHITE,
+		castling:       "-",
+		fullmoveNumber: 1,
+	}
+	errs := []*Error{}
+
+	switch len(fields) {
+	case 1, 4, 6:
+	case 0:
+		return nil, []*Error{wrapError(ErrBadFieldCount, "invalid fen %q: fen is empty", fen)}
+	default:
+		errs = append(errs, wrapError(ErrBadFieldCount, "invalid fen %q: expected 6 fields. Got %d", fen, len(fields)))
+	}
+
+	errs = append(errs, parsePlacement(fields[0], pos)...)
+
+	if len(fields) > 1 {
+		switch fields[1] {
+		case "w":
+			pos.turn = WHITE
+		case "b":
+			pos.turn = BLACK
+		default:
+			errs = append(errs, wrapError(ErrBadSideToMove, "invalid fen side to move %q", fields[1]))
+		}
+	}
+
+	if len(fields) > 2 {
+		if err := parseCastling(fields[2]); err != nil {
+			errs = append(errs, err)
+		} else {
+			pos.castling = fields[2]
+		}
+	}
+
+	if len(fields) > 3 && fields[3] != "-" {
+		row, col, ok := parseSquareName(fields[3])
+		if !ok || (row != ROW_3 && row != ROW_6) {
+			errs = append(errs, wrapError(ErrBadEnPassant, "invalid fen en passant square %q", fields[3]))
+		} else {
+			pos.enPassant = [2]int{row, col}
+			pos.hasEnPassant = true
+		}
+	}
+
+	if len(fields) > 4 {
+		halfmove, err := strconv.Atoi(fields[4])
+		if err != nil || halfmove < 0 {
+			errs = append(errs, wrapError(ErrBadHalfmoveClock, "invalid fen halfmove clock %q", fields[4]))
+		}
+		pos.halfmoveClock = halfmove
+	}
+
+	if len(fields) > 5 {
+		fullmove, err := strconv.Atoi(fields[5])
+		if err != nil || fullmove < 1 {
+			errs = append(errs, wrapError(ErrBadFullmoveNumber, "invalid fen fullmove number %q", fields[5]))
+		}
+		pos.fullmoveNumber = fullmove
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return pos, nil
+}
+
+func parsePlacement(placement string, pos *fenPosition) []*Error {
+	ranks := strings.Split(placement, "/")
+	if len(ranks) != 8 {
+		return []*Error{wrapError(ErrBadRankCount, "invalid fen placement %q: expected 8 ranks. Got %d", placement, len(ranks))}
+	}
+
+	errs := []*Error{}
+	for i, rank := range ranks {
+		col := 0
+		for _, ch := range rank {
+			switch {
+			case ch >= '1' && ch <= '8':
+				col += int(ch - '0')
+			case fenPieces[toLowerRune(ch)] != "":
+				if col < 8 {
+					pos.placement[i][col] = ch
+				}
+				col++
+			default:
+				errs = append(errs, wrapError(ErrBadPieceSymbol, "invalid fen placement %q: unknown piece %q on rank %d", placement, ch, 8-i))
+			}
+		}
+		if col != 8 {
+			errs = append(errs, wrapError(ErrBadRankLength, "invalid fen placement %q: rank %d has %d squares", placement, 8-i, col))
+		}
+	}
+	return errs
+}
+
+func parseCastling(castling string) *Error {
+	if castling == "-" {
+		return nil
+	}
+	order := "KQkq"
+	last := -1
+	for _, right := range castling {
+		idx := strings.IndexRune(order, right)
+		if idx <= last {
+			return wrapError(ErrBadCastlingRights, "invalid fen castling rights %q", castling)
+		}
+		last = idx
+	}
+	return nil
+}
+
+// parseSquareName converts a square name such as "e3" or "E3" into board
+// coordinates.
+func parseSquareName(name string) (int, int, bool) {
+	if len(name) != 2 {
+		return 0, 0, false
+	}
+	col := int(toLowerRune(rune(name[0])) - 'a')
+	row := int('8' - name[1])
+	if !squareExists(row, col) {
+		return 0, 0, false
+	}
+	return row, col, true
+}
+
+func toLowerRune(ch rune) rune {
+	if ch >= 'A' && ch <= 'Z' {
+		return ch + ('a' - 'A')
+	}
+	return ch
+}
@@ -0,0 +1,82 @@
+package board
+
+// DrawByInsufficientMaterial reports whether neither side can checkmate by
+// any series of legal moves, as with K v K, K+N v K, K+B v K or kings and
+// bishops that all stand on squares of one color.
+func (b *Board) DrawByInsufficientMaterial() bool {
+	return !b.CanCheckmate(WHITE) && !b.CanCheckmate(BLACK)
+}
+
+// TimeoutIsDraw reports whether running out of time draws the game for
+// color instead of losing it, because the opponent could never checkmate.
+func (b *Board) TimeoutIsDraw(color string) bool {
+	return !b.CanCheckmate(ENEMY[color])
+}
+
+// CanCheckmate reports whether color could checkmate with the help of the
+// opponent's moves. A lone knight or bishops on one square color can only
+// mate when the opponent has pieces to block its own king in.
+func (b *Board) CanCheckmate(color string) bool {
+	own := b.materialCount(color)
+	switch {
+	case own.pawns+own.rooks+own.queens > 0:
+		return true
+	case own.knights == 0 && own.lightBishops+own.darkBishops == 0:
+		return false
+	case own.knights > 1 || (own.knights == 1 && own.lightBishops+own.darkBishops > 0):
+		return true
+	case own.lightBishops > 0 && own.darkBishops > 0:
+		return true
+	}
+
+	enemy := b.materialCount(ENEMY[color])
+	if own.knights == 1 {
+		return enemy.pieces() > 0
+	}
+	blockers := enemy.pawns + enemy.knights + enemy.rooks + enemy.queens
+	if own.lightBishops > 0 {
+		return blockers+enemy.darkBishops > 0
+	}
+	return blockers+enemy.lightBishops > 0
+}
+
+type material struct {
+	pawns        int
+	knights      int
+	lightBishops int
+	darkBishops  int
+	rooks        int
+	queens       int
+}
+
+func (m material) pieces() int {
+	return m.pawns + m.knights + m.lightBishops + m.darkBishops + m.rooks + m.queens
+}
+
+func (b *Board) materialCount(color string) material {
+	count := material{}
+	for piece := range b.getAllies(color) {
+		switch piece.Type() {
+		case PAWN:
+			count.pawns++
+		case KNIGHT:
+			count.knights++
+		case BISHOP:
+			if isLightSquare(piece.Square()) {
+				count.lightBishops++
+			} else {
+				count.darkBishops++
+			}
+		case ROOK:
+			count.rooks++
+		case QUEEN:
+			count.queens++
+		}
+	}
+	return count
+}
+
+// isLightSquare reports the square color; a1 (row 7, column 0) is dark.
+func isLightSquare(sq *Square) bool {
+	return (sq.Row+sq.Column)%2 == 0
+}
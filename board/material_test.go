@@ -0,0 +1,61 @@
+package board
+
+import "testing"
+
+func TestDrawByInsufficientMaterial(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		expected    bool
+		whiteCanWin bool
+		blackCanWin bool
+	}{
+		{"K v K", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", true, false, false},
+		{"K+N v K", "4k3/8/8/8/8/8/8/4KN2 w - - 0 1", true, false, false},
+		{"K v K+N", "4k1n1/8/8/8/8/8/8/4K3 w - - 0 1", true, false, false},
+		{"K+B v K", "4k3/8/8/8/8/8/8/4KB2 w - - 0 1", true, false, false},
+		{"K v K+B", "2b1k3/8/8/8/8/8/8/4K3 w - - 0 1", true, false, false},
+		{"K+B v K+B same color", "4kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", true, false, false},
+		{"K+BB v K same color", "4k3/8/8/8/8/8/8/B1B1K3 w - - 0 1", true, false, false},
+		{"K+B v K+B opposite color", "4k1b1/8/8/8/8/8/8/2B1K3 w - - 0 1", false, true, true},
+		{"K+BB v K opposite colors", "4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1", false, true, false},
+		{"K+N v K+N", "4k1n1/8/8/8/8/8/8/4KN2 w - - 0 1", false, true, true},
+		{"K+B v K+N", "4k1n1/8/8/8/8/8/8/4KB2 w - - 0 1", false, true, true},
+		{"K+NN v K", "4k3/8/8/8/8/8/8/1N2K1N1 w - - 0 1", false, true, false},
+		{"K+BN v K", "4k3/8/8/8/8/8/8/1N2KB2 w - - 0 1", false, true, false},
+		{"K+N v K+P", "4k3/4p3/8/8/8/8/8/4KN2 w - - 0 1", false, true, true},
+		{"K+B v K+P", "4k3/4p3/8/8/8/8/8/4KB2 w - - 0 1", false, true, true},
+		{"K+P v K", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", false, true, false},
+		{"K+R v K", "4k3/8/8/8/8/8/8/4K2R w - - 0 1", false, true, false},
+		{"K+Q v K", "4k3/8/8/8/8/8/8/3QK3 w - - 0 1", false, true, false},
+		{"K+N v K+R", "4k2r/8/8/8/8/8/8/4KN2 w - - 0 1", false, true, true},
+		{"start position", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", false, true, true},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		if draw := board.DrawByInsufficientMaterial(); draw != tt.expected {
+			t.Fatalf("%s: insufficient material should be %t. Got %t", tt.name, tt.expected, draw)
+		}
+		if canWin := board.CanCheckmate(WHITE); canWin != tt.whiteCanWin {
+			t.Fatalf("%s: white can checkmate should be %t. Got %t", tt.name, tt.whiteCanWin, canWin)
+		}
+		if canWin := board.CanCheckmate(BLACK); canWin != tt.blackCanWin {
+			t.Fatalf("%s: black can checkmate should be %t. Got %t", tt.name, tt.blackCanWin, canWin)
+		}
+		if board.TimeoutIsDraw(BLACK) != !tt.whiteCanWin || board.TimeoutIsDraw(WHITE) != !tt.blackCanWin {
+			t.Fatalf("%s: timeout draws should follow the opponent's mating chances", tt.name)
+		}
+	}
+}
+
+func TestInsufficientMaterialAfterCapture(t *testing.T) {
+	board := New()
+	board.SetupFromFen("4k3/8/8/8/8/8/3r4/4KN2 w - - 0 1")
+	board.Evaluate(BLACK)
+	playSans(t, board, "Kxd2")
+	if !board.Draw || board.DrawReason() != DRAW_INSUFFICIENT_MATERIAL {
+		t.Fatalf("K+N v K should be drawn. Got draw %t (%q)", board.Draw, board.DrawReason())
+	}
+}
@@ -1,6 +1,7 @@
 package board
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -16,6 +17,9 @@ const (
 	CHECK      = "CHECK"
 )
 
+// ErrWrongTurn is returned by MovePiece for a move by the side not to move.
+var ErrWrongTurn = errors.New("wrong side to move")
+
 var centerSquares = make(map[*Square]bool)
 
 type SqActivity string
@@ -28,35 +32,88 @@ type Move struct {
 	To        *Square
 	Promotion Piece
 	Value     float64
+
+	prevState gameState
+}
+
+// gameState is the part of the position that can't be recovered from the
+// pieces alone. Moves keep a copy so UndoMove can restore it.
+type gameState struct {
+	turn           string
+	enPassant      *Square
+	halfmoveClock  int
+	fullmoveNumber int
+	hash           uint64
 }
 
 func (b *Board) GetAllValidMoves(color string) []*Move {
-	moves := []*Move{}
-	pieces := b.getAllies(color)
-	for piece := range pieces {
-		for sq, activity := range piece.ActiveSquares() {
-			if activity != GUARDED {
-				move := &Move{
-					Turn:  color,
-					Piece: piece,
-					From:  piece.Square(),
-					To:    sq,
-				}
-				move.Evaluate(activity, b)
+	return b.generateMoves(color, false)
+}
 
-				if move.Piece.Type() == PAWN && (move.To.Row == ROW_1 || move.To.Row == ROW_8) {
-					move.Promotion = &Queen{color: color}
+// generateMoves returns the legal moves of color, highest valued first. With
+// noisy set only captures and promotions to a queen are returned, the moves
+// that can change the material balance.
+func (b *Board) generateMoves(color string, noisy bool) []*Move {
+	moves := []*Move{}
+	for bb := b.bb.occupied[colorIndex(color)]; bb != 0; bb &= bb - 1 {
+		piece := b.squareAt(bb.first()).Piece
+		set := activesOf(piece)
+		if set == nil || !piece.IsAlly(color) {
+			continue
+		}
+		targets := set.moves()
+		if noisy {
+			targets = set.capture | set.enPassant
+			if piece.Type() == PAWN {
+				targets |= set.free & (rankMask(ROW_1) | rankMask(ROW_8))
+			}
+		}
+		for ; targets != 0; targets &= targets - 1 {
+			idx := targets.first()
+			activity, _ := set.activity(idx)
+			move := &Move{
+				Turn:  color,
+				Piece: piece,
+				From:  piece.Square(),
+				To:    b.squareAt(idx),
+			}
+			move.Evaluate(activity, b)
+
+			if move.IsPromotion() {
+				if noisy {
+					move.Promotion = b.CreatePiece(color, QUEEN)
+					move.Value += PieceValues[QUEEN] - PieceValues[PAWN]
+					moves = append(moves, move)
+					continue
 				}
-				moves = append(moves, move)
+				moves = append(moves, b.promotionMoves(move)...)
+				continue
 			}
+			moves = append(moves, move)
 		}
 	}
-	sort.Slice(moves, func(i, j int) bool {
+	sort.SliceStable(moves, func(i, j int) bool {
 		return moves[i].Value > moves[j].Value
 	})
 	return moves
 }
 
+// PromotionTypes lists the pieces a pawn may promote to, strongest first.
+var PromotionTypes = []string{QUEEN, ROOK, BISHOP, KNIGHT}
+
+// promotionMoves expands a pawn move to the last rank into one move per
+// promotion piece. The gain in material is added to each move's value.
+func (b *Board) promotionMoves(move *Move) []*Move {
+	moves := []*Move{}
+	for _, pieceType := range PromotionTypes {
+		promotion := *move
+		promotion.Promotion = b.CreatePiece(move.Turn, pieceType)
+		promotion.Value += PieceValues[pieceType] - PieceValues[PAWN]
+		moves = append(moves, &promotion)
+	}
+	return moves
+}
+
 func (m *Move) Evaluate(activity SqActivity, board *Board) {
 	switch activity {
 	case CASTLE:
@@ -115,17 +172,24 @@ func (m *Move) Copy(simBoard *Board) *Move {
 	toCol := m.To.Column
 	simFrom := simBoard.Squares[fromRow][fromCol]
 	simTo := simBoard.Squares[toRow][toCol]
-	return &Move{
+	simMove := &Move{
 		Turn:  m.Turn,
 		Piece: simFrom.Piece,
 		From:  simFrom,
 		To:    simTo,
 	}
+	if m.Promotion != nil {
+		simMove.Promotion = simBoard.CreatePiece(m.Promotion.Color(), m.Promotion.Type())
+	}
+	return simMove
 }
 
 func (m *Move) IsValid(board *Board) bool {
-	valids := m.Piece.ActiveSquares()
-	moveType, ok := valids[m.To]
+	set := activesOf(m.Piece)
+	if set == nil {
+		return false
+	}
+	moveType, ok := set.activity(squareIndex(m.To))
 	if ok {
 		m.Type = moveType
 		return true
@@ -133,41 +197,98 @@ func (m *Move) IsValid(board *Board) bool {
 	return false
 }
 
+// CheckMove returns the error MovePiece would give move without playing it:
+// the move is out of turn or the piece can't make it.
+func (b *Board) CheckMove(move *Move) (string, *Error) {
+	// an empty square is no one's to move, so it is just an invalid move
+	if move.Piece.Type() != NULL && move.Piece.Color() != b.Turn {
+		turnErr := wrapError(ErrWrongTurn, "It is %s's turn to move", b.Turn)
+		return turnErr.Message, turnErr
+	}
+	if !move.IsValid(b) {
+		return b.invalidMove(move)
+	}
+	return "", nil
+}
+
 func (b *Board) MovePiece(move *Move) (string, *Error) {
+	if receipt, err := b.CheckMove(move); err != nil {
+		return receipt, err
+	}
 	receipt := ""
-	if move.IsValid(b) {
-		move.Piece.IncrementMoveCount()
+	move.prevState = gameState{
+		turn:           b.Turn,
+		enPassant:      b.EnPassant,
+		halfmoveClock:  b.HalfmoveClock,
+		fullmoveNumber: b.FullmoveNumber,
+		hash:           b.hash,
+	}
+	prevKey := b.stateKey()
 
-		switch move.Type {
-		case FREE:
-			receipt = b.executeFreeMove(move)
-			b.Receipts = append(b.Receipts, receipt)
-			b.addFen(b.Fen())
-			b.Evaluate(move.Turn)
-			return receipt, nil
-		case CAPTURE:
-			receipt = b.executeCaptureMove(move)
-			b.Receipts = append(b.Receipts, receipt)
-			b.Evaluate(move.Turn)
-			return receipt, nil
-		case EN_PASSANT:
-			receipt = b.executeEnPassantMove(move)
-			b.Receipts = append(b.Receipts, receipt)
-			b.Evaluate(move.Turn)
-			return receipt, nil
-		case CASTLE:
-			receipt = b.executeCastleMove(move)
-			b.Receipts = append(b.Receipts, receipt)
-			b.Evaluate(move.Turn)
-			return receipt, nil
-		}
+	switch move.Type {
+	case FREE:
+		move.Piece.IncrementMoveCount()
+		b.Hashes = append(b.Hashes, b.hash)
+		receipt = b.executeFreeMove(move)
+		b.Receipts = append(b.Receipts, receipt)
+		b.updateGameState(move)
+		b.hash ^= prevKey ^ b.moveKey(move) ^ b.stateKey()
+		b.Evaluate(move.Turn)
+		return receipt, nil
+	case CAPTURE:
+		move.Piece.IncrementMoveCount()
+		b.Hashes = append(b.Hashes, b.hash)
+		receipt = b.executeCaptureMove(move)
+		b.Receipts = append(b.Receipts, receipt)
+		b.updateGameState(move)
+		b.hash ^= prevKey ^ b.moveKey(move) ^ b.stateKey()
+		b.Evaluate(move.Turn)
+		return receipt, nil
+	case EN_PASSANT:
+		move.Piece.IncrementMoveCount()
+		b.Hashes = append(b.Hashes, b.hash)
+		receipt = b.executeEnPassantMove(move)
+		b.Receipts = append(b.Receipts, receipt)
+		b.updateGameState(move)
+		b.hash ^= prevKey ^ b.moveKey(move) ^ b.stateKey()
+		b.Evaluate(move.Turn)
+		return receipt, nil
+	case CASTLE:
+		move.Piece.IncrementMoveCount()
+		b.Hashes = append(b.Hashes, b.hash)
+		receipt = b.executeCastleMove(move)
+		b.Receipts = append(b.Receipts, receipt)
+		b.updateGameState(move)
+		b.hash ^= prevKey ^ b.moveKey(move) ^ b.stateKey()
+		b.Evaluate(move.Turn)
+		return receipt, nil
 	}
 	return b.invalidMove(move)
 }
 
+// updateGameState advances the side to move, the en passant target and the
+// move clocks after move has been executed.
+func (b *Board) updateGameState(move *Move) {
+	color := move.Piece.Color()
+	b.Turn = ENEMY[color]
+
+	b.EnPassant = nil
+	if move.Piece.Type() == PAWN && calcOffset(move.From.Row, move.To.Row) == 2 {
+		b.EnPassant = b.Squares[(move.From.Row+move.To.Row)/2][move.From.Column]
+	}
+
+	if move.Piece.Type() == PAWN || move.Type == CAPTURE || move.Type == EN_PASSANT {
+		b.HalfmoveClock = 0
+	} else {
+		b.HalfmoveClock++
+	}
+	if color == BLACK {
+		b.FullmoveNumber++
+	}
+}
+
 func (b *Board) UndoMove() {
 	last := b.LastMove()
-	prevFen := b.Fen()
 	if last.Promotion != nil {
 		b.RemovePiece(last.Promotion, last.To)
 		b.SetPiece(last.Piece, last.From)
@@ -183,16 +304,25 @@ func (b *Board) UndoMove() {
 		b.undoCastle(last)
 	}
 	last.Piece.DecrementMoveCount()
+	b.restoreGameState(last)
 	b.resetCheck(last.Turn)
 	b.Checkmate = false
 	b.Stalemate = false
 	b.Draw = false
 	b.removeLastReceipt()
-	b.removeLastFen(prevFen)
+	b.removeLastHash()
 	b.removeLastMove()
 	b.Evaluate(ENEMY[last.Turn])
 }
 
+func (b *Board) restoreGameState(last *Move) {
+	b.Turn = last.prevState.turn
+	b.EnPassant = last.prevState.enPassant
+	b.HalfmoveClock = last.prevState.halfmoveClock
+	b.FullmoveNumber = last.prevState.fullmoveNumber
+	b.hash = last.prevState.hash
+}
+
 func (b *Board) pawnPromoted(last *Move) bool {
 	return last.Piece.Type() == PAWN && (last.To.Row == ROW_1 || last.To.Row == ROW_8)
 }
@@ -236,6 +366,9 @@ func (b *Board) undoCastle(move *Move) {
 	move.From.SetPiece(move.Piece)
 	move.Piece.SetSquare(move.From)
 	move.To.SetPiece(&Null{})
+	if king, ok := move.Piece.(*King); ok {
+		king.Castled = false
+	}
 
 	b.resetCastledRook(move)
 }
@@ -248,11 +381,9 @@ func (b *Board) removeLastMove() {
 	b.Moves = b.Moves[:len(b.Moves)-1]
 }
 
-func (b *Board) removeLastFen(prevFen string) {
-	if b.Fens[prevFen] < 2 {
-		delete(b.Fens, prevFen)
-	} else {
-		b.Fens[prevFen]--
+func (b *Board) removeLastHash() {
+	if len(b.Hashes) > 0 {
+		b.Hashes = b.Hashes[:len(b.Hashes)-1]
 	}
 }
 
@@ -301,14 +432,6 @@ func (b *Board) resetCastledRook(last *Move) {
 	}
 }
 
-func (b *Board) addFen(fen string) {
-	if _, ok := b.Fens[fen]; ok {
-		b.Fens[fen] += 1
-		return
-	}
-	b.Fens[fen] = 1
-}
-
 func (b *Board) invalidMove(move *Move) (string, *Error) {
 	gameError := NewError(
 		"%s: %s -> %s is not a valid move",
@@ -360,17 +483,19 @@ func (b *Board) executeCaptureMove(move *Move) string {
 
 }
 
+// executePawnPromotion replaces the pawn with the piece named by
+// move.Promotion, or a queen when no promotion was chosen. A fresh piece is
+// placed every time so a move can be played, undone and replayed.
 func (b *Board) executePawnPromotion(move *Move, receipt string) string {
+	pieceType := move.promotionType()
 	b.RemovePiece(move.From.Piece, move.From)
-	queen := &Queen{color: move.Turn}
-	queen.SetValue()
-	b.SetPiece(queen, move.To)
-	move.Promotion = queen
+	promoted := b.CreatePiece(move.Piece.Color(), pieceType)
+	b.SetPiece(promoted, move.To)
+	move.Promotion = promoted
 	b.Moves = append(b.Moves, move)
 
-	receipt += fmt.Sprintf(" (PROMOTION: QUEEN)")
+	receipt += fmt.Sprintf(" (PROMOTION: %s)", pieceType)
 	return receipt
-
 }
 
 func (b *Board) executeEnPassantMove(move *Move) string {
@@ -0,0 +1,313 @@
+package board
+
+// activeSet holds the squares a piece acts on, one bitboard per activity.
+// The ActiveSquares map is only built when it is asked for, since search
+// code works from the bitboards.
+type activeSet struct {
+	board     *Board
+	free      Bitboard
+	capture   Bitboard
+	guarded   Bitboard
+	enPassant Bitboard
+	castle    Bitboard
+	squares   map[*Square]SqActivity
+}
+
+// moves returns the squares the piece can legally move to.
+func (a *activeSet) moves() Bitboard {
+	return a.free | a.capture | a.enPassant | a.castle
+}
+
+func (a *activeSet) activity(idx int) (SqActivity, bool) {
+	switch {
+	case a.free.Has(idx):
+		return FREE, true
+	case a.capture.Has(idx):
+		return CAPTURE, true
+	case a.enPassant.Has(idx):
+		return EN_PASSANT, true
+	case a.castle.Has(idx):
+		return CASTLE, true
+	case a.guarded.Has(idx):
+		return GUARDED, true
+	}
+	return "", false
+}
+
+func (a *activeSet) activeSquares() map[*Square]SqActivity {
+	if a.board == nil {
+		return nil
+	}
+	if a.squares != nil {
+		return a.squares
+	}
+	a.squares = make(map[*Square]SqActivity)
+	for bb := a.moves() | a.guarded; bb != 0; bb &= bb - 1 {
+		idx := bb.first()
+		activity, _ := a.activity(idx)
+		a.squares[a.board.squareAt(idx)] = activity
+	}
+	return a.squares
+}
+
+func activesOf(piece Piece) *activeSet {
+	switch p := piece.(type) {
+	case *King:
+		return &p.actives
+	case *Queen:
+		return &p.actives
+	case *Rook:
+		return &p.actives
+	case *Bishop:
+		return &p.actives
+	case *Knight:
+		return &p.actives
+	case *Pawn:
+		return &p.actives
+	default:
+		return nil
+	}
+}
+
+// computeActives works out the active squares of piece from the bitboards.
+// Check and pin information for the piece's side must already be set.
+func (b *Board) computeActives(piece Piece) activeSet {
+	set := activeSet{board: b}
+	idx := squareIndex(piece.Square())
+	color := colorIndex(piece.Color())
+	kind := kindIndex(piece.Type())
+	own := b.bb.occupied[color]
+	enemies := b.bb.occupied[1-color]
+
+	if kind == KING_INDEX {
+		b.computeKingActives(piece.(*King), idx, color, &set)
+		return set
+	}
+
+	king := b.kingOf(color)
+	if king != nil && king.Checked && len(king.Checkers) > 1 {
+		return set
+	}
+
+	attacks := pieceAttacks(kind, color, idx, b.bb.all)
+	set.capture = attacks & enemies
+	set.guarded = attacks & own
+	if kind == PAWN_INDEX {
+		set.guarded |= attacks &^ b.bb.all
+		set.free = b.pawnPushes(idx, color)
+		b.addEnPassant(idx, color, &set)
+	} else {
+		set.free = attacks &^ b.bb.all
+		set.guarded |= b.kingXRay(kind, idx, color, attacks)
+	}
+
+	if king != nil && king.Checked {
+		kingIdx := squareIndex(king.Square())
+		checkerIdx := squareIndex(king.Checkers[0].Square())
+		mask := between[kingIdx][checkerIdx] | squareBit(checkerIdx)
+		enPassant := set.enPassant
+		set.free &= mask
+		set.capture &= mask
+		set.guarded &= mask
+		set.enPassant &= mask
+		if king.Checkers[0].Type() == PAWN && b.enPassantVictim(color).Has(checkerIdx) {
+			set.enPassant = enPassant
+		}
+	}
+
+	if pin := piece.Pin(); pin != nil && king != nil {
+		kingIdx := squareIndex(king.Square())
+		pinnerIdx := squareIndex(pin.Piece.Square())
+		path := between[kingIdx][pinnerIdx] | squareBit(pinnerIdx)
+		switch kind {
+		case KNIGHT_INDEX:
+			return activeSet{board: b}
+		case PAWN_INDEX:
+			set.guarded = 0
+		default:
+			set.guarded |= (set.free | set.capture) &^ path
+		}
+		set.free &= path
+		set.capture &= path
+		set.enPassant &= path
+	}
+	return set
+}
+
+func (b *Board) pawnPushes(idx, color int) Bitboard {
+	row, col := idx/8, idx%8
+	step, startRow := -1, ROW_2
+	if color == BLACK_INDEX {
+		step, startRow = 1, ROW_7
+	}
+	if !squareExists(row+step, col) {
+		return 0
+	}
+	single := squareBit((row+step)*8 + col)
+	if single&b.bb.all != 0 {
+		return 0
+	}
+	if row != startRow {
+		return single
+	}
+	double := squareBit((row+2*step)*8 + col)
+	if double&b.bb.all != 0 {
+		return single
+	}
+	return single | double
+}
+
+// enPassantVictim returns the square of the pawn that an en passant capture
+// by color would take.
+func (b *Board) enPassantVictim(color int) Bitboard {
+	if b.EnPassant == nil {
+		return 0
+	}
+	if color == WHITE_INDEX {
+		return squareBit(squareIndex(b.EnPassant) + 8)
+	}
+	return squareBit(squareIndex(b.EnPassant) - 8)
+}
+
+// addEnPassant moves the en passant target from the pawn's guarded squares
+// to its en passant squares when taking there would not expose its king.
+func (b *Board) addEnPassant(idx, color int, set *activeSet) {
+	if b.EnPassant == nil {
+		return
+	}
+	targetRow := ROW_6
+	if color == BLACK_INDEX {
+		targetRow = ROW_3
+	}
+	target := squareIndex(b.EnPassant)
+	if b.EnPassant.Row != targetRow || !pawnAttacks[color][idx].Has(target) {
+		return
+	}
+	victim := b.enPassantVictim(color)
+	if b.bb.pieces[1-color][PAWN_INDEX]&victim == 0 {
+		return
+	}
+	kings := b.bb.pieces[color][KING_INDEX]
+	if kings != 0 {
+		occupied := b.bb.all&^squareBit(idx)&^victim | squareBit(target)
+		enemies := b.bb.pieces[1-color]
+		diagonals := enemies[BISHOP_INDEX] | enemies[QUEEN_INDEX]
+		straights := enemies[ROOK_INDEX] | enemies[QUEEN_INDEX]
+		kingIdx := kings.first()
+		if bishopAttacks(kingIdx, occupied)&diagonals != 0 || rookAttacks(kingIdx, occupied)&straights != 0 {
+			return
+		}
+	}
+	set.guarded &^= squareBit(target)
+	set.enPassant = squareBit(target)
+}
+
+// kingXRay returns the square behind the enemy king when a slider attacks
+// it, so the king can't step back along the line of the check.
+func (b *Board) kingXRay(kind, idx, color int, attacks Bitboard) Bitboard {
+	if kind != BISHOP_INDEX && kind != ROOK_INDEX && kind != QUEEN_INDEX {
+		return 0
+	}
+	enemyKing := b.bb.pieces[1-color][KING_INDEX] & attacks
+	if enemyKing == 0 {
+		return 0
+	}
+	kingIdx := enemyKing.first()
+	behind := pieceAttacks(kind, color, idx, b.bb.all&^enemyKing)
+	return behind & lines[idx][kingIdx] & kingAttacks[kingIdx] &^ attacks &^ squareBit(idx)
+}
+
+func (b *Board) computeKingActives(king *King, idx, color int, set *activeSet) {
+	own := b.bb.occupied[color]
+	enemies := b.bb.occupied[1-color]
+	unsafe := b.attackedBy(1-color, b.bb.all&^squareBit(idx))
+	targets := kingAttacks[idx] &^ own &^ unsafe
+	set.free = targets &^ enemies
+	set.capture = targets & enemies
+
+	if king.HasMoved() || king.Checked {
+		return
+	}
+	row := ROW_1
+	if color == BLACK_INDEX {
+		row = ROW_8
+	}
+	if idx != row*8+COL_E {
+		return
+	}
+	castles := []struct {
+		rookCol int
+		empty   []int
+		safe    []int
+		to      int
+	}{
+		{COL_H, []int{COL_F, COL_G}, []int{COL_F, COL_G}, COL_G},
+		{COL_A, []int{COL_B, COL_C, COL_D}, []int{COL_C, COL_D}, COL_C},
+	}
+	for _, castle := range castles {
+		rook, ok := b.Squares[row][castle.rookCol].Piece.(*Rook)
+		if !ok || !rook.IsAlly(king.color) || rook.HasMoved() {
+			continue
+		}
+		if b.bb.all&rankSquares(row, castle.empty) != 0 || unsafe&rankSquares(row, castle.safe) != 0 {
+			continue
+		}
+		set.castle |= squareBit(row*8 + castle.to)
+	}
+}
+
+func rankSquares(row int, cols []int) Bitboard {
+	bb := Bitboard(0)
+	for _, col := range cols {
+		bb |= squareBit(row*8 + col)
+	}
+	return bb
+}
+
+// setPins marks every piece of color that is pinned to its king by an enemy
+// slider.
+func (b *Board) setPins(color string) {
+	c := colorIndex(color)
+	kings := b.bb.pieces[c][KING_INDEX]
+	if kings == 0 {
+		return
+	}
+	kingIdx := kings.first()
+	enemies := b.bb.pieces[1-c]
+	pinners := rookAttacks(kingIdx, 0)&(enemies[ROOK_INDEX]|enemies[QUEEN_INDEX]) |
+		bishopAttacks(kingIdx, 0)&(enemies[BISHOP_INDEX]|enemies[QUEEN_INDEX])
+
+	for ; pinners != 0; pinners &= pinners - 1 {
+		pinnerIdx := pinners.first()
+		blockers := between[kingIdx][pinnerIdx] & b.bb.all
+		if blockers.Count() != 1 || blockers&b.bb.occupied[c] == 0 {
+			continue
+		}
+		path := map[*Square]bool{}
+		for _, sq := range (between[kingIdx][pinnerIdx] | squareBit(pinnerIdx) | squareBit(kingIdx)).Squares(b) {
+			path[sq] = true
+		}
+		pinned := b.squareAt(blockers.first()).Piece
+		pinned.SetPin(b.squareAt(pinnerIdx).Piece, path)
+	}
+}
+
+// kingOf returns the king of color found from the bitboards, or nil.
+func (b *Board) kingOf(color int) *King {
+	kings := b.bb.pieces[color][KING_INDEX]
+	if kings == 0 {
+		return nil
+	}
+	king, _ := b.squareAt(kings.first()).Piece.(*King)
+	return king
+}
+
+// hasLegalMove reports whether any piece of color can move.
+func (b *Board) hasLegalMove(color string) bool {
+	for piece := range b.getAllies(color) {
+		if set := activesOf(piece); set != nil && set.moves() != 0 {
+			return true
+		}
+	}
+	return false
+}
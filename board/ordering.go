@@ -0,0 +1,130 @@
+package board
+
+import "sort"
+
+// Move ordering scores. The search tries moves in this order so that the
+// move most likely to cause a cutoff comes first: the move from the
+// transposition table, then captures that don't lose material and
+// promotions, then the killer moves of the ply, then the other quiet moves
+// by their history, and last the captures that lose material.
+const (
+	ORDER_TT_MOVE     = 1 << 30
+	ORDER_NOISY       = 1 << 28
+	ORDER_KILLER      = 1 << 26
+	ORDER_BAD_CAPTURE = -(1 << 20)
+	// HISTORY_MAX caps the history score of a quiet move, so it never
+	// reaches the killers. The table is halved when an entry would pass it.
+	HISTORY_MAX = 1 << 24
+)
+
+// capturedKind returns the kindIndex of the piece move takes, or -1 if it
+// takes nothing.
+func capturedKind(move *Move) int {
+	if victim := move.To.Piece; victim.Type() != NULL {
+		return kindIndex(victim.Type())
+	}
+	if move.Piece.Type() == PAWN && move.From.Column != move.To.Column {
+		return kindIndex(PAWN)
+	}
+	return -1
+}
+
+// isQuiet reports whether move neither captures nor promotes.
+func isQuiet(move *Move) bool {
+	return capturedKind(move) < 0 && !move.IsPromotion()
+}
+
+// mvvLva scores a capture by the most valuable victim, and among captures
+// of the same victim by the least valuable attacker.
+func mvvLva(move *Move) int {
+	return 8*capturedKind(move) + 5 - kindIndex(move.Piece.Type())
+}
+
+// losesMaterial reports whether move loses material by SEE. Taking a piece
+// worth at least the one taking never does.
+func (b *Board) losesMaterial(move *Move) bool {
+	kind := capturedKind(move)
+	if kind >= 0 && !move.IsPromotion() && kindValue(kind) >= kindValue(kindIndex(move.Piece.Type())) {
+		return false
+	}
+	return b.SEE(move) < 0
+}
+
+// orderScore returns how early move is searched at ply.
+func (b *Board) orderScore(move *Move, hashMove ttMove, ply int) int {
+	s := b.search
+	packed := packMove(move)
+	if !hashMove.isNull() && packed == hashMove {
+		return ORDER_TT_MOVE
+	}
+	if move.IsPromotion() {
+		// queen promotions before every capture, under-promotions last
+		if move.promotionType() == QUEEN {
+			return ORDER_NOISY + 64 + max(capturedKind(move), 0)
+		}
+		return kindIndex(move.promotionType()) - 8
+	}
+	if capturedKind(move) >= 0 {
+		if b.losesMaterial(move) {
+			return ORDER_BAD_CAPTURE + mvvLva(move)
+		}
+		return ORDER_NOISY + mvvLva(move)
+	}
+	if s == nil {
+		return 0
+	}
+	if ply < len(s.killers) {
+		for i, killer := range s.killers[ply] {
+			if packed == killer {
+				return ORDER_KILLER - i
+			}
+		}
+	}
+	return s.history[colorIndex(move.Turn)][packed.from][packed.to]
+}
+
+// orderMoves sorts moves at ply so the most promising are searched first.
+// Moves scored the same keep their order.
+func (b *Board) orderMoves(moves []*Move, hashMove ttMove, ply int) {
+	type scoredMove struct {
+		move  *Move
+		score int
+	}
+	scored := make([]scoredMove, len(moves))
+	for i, move := range moves {
+		scored[i] = scoredMove{move, b.orderScore(move, hashMove, ply)}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	for i := range scored {
+		moves[i] = scored[i].move
+	}
+}
+
+// recordCutoff remembers a quiet move that caused a beta cutoff at ply, as
+// a killer of the ply and in the history of its squares. Deeper searches
+// count for more.
+func (s *searchState) recordCutoff(move *Move, ply, depth int) {
+	if s == nil || !isQuiet(move) {
+		return
+	}
+	packed := packMove(move)
+	for len(s.killers) <= ply {
+		s.killers = append(s.killers, [2]ttMove{})
+	}
+	if s.killers[ply][0] != packed {
+		s.killers[ply][1] = s.killers[ply][0]
+		s.killers[ply][0] = packed
+	}
+
+	side := &s.history[colorIndex(move.Turn)]
+	side[packed.from][packed.to] += depth * depth
+	if side[packed.from][packed.to] > HISTORY_MAX {
+		for from := range side {
+			for to := range side[from] {
+				side[from][to] /= 2
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package board
+
+import (
+	"slices"
+	"testing"
+)
+
+func findMove(t *testing.T, moves []*Move, uci string) *Move {
+	t.Helper()
+	for _, move := range moves {
+		if move.Uci() == uci {
+			return move
+		}
+	}
+	t.Fatalf("no move %s", uci)
+	return nil
+}
+
+func uciMoves(moves []*Move) []string {
+	ucis := []string{}
+	for _, move := range moves {
+		ucis = append(ucis, move.Uci())
+	}
+	return ucis
+}
+
+func TestOrderMoves(t *testing.T) {
+	board := searchBoard(t, "4k3/8/8/3q4/p3P3/8/8/3QK3 w - - 0 1")
+	moves := board.GetAllValidMoves(WHITE)
+	count := len(moves)
+
+	board.search = &searchState{}
+	board.search.recordCutoff(findMove(t, moves, "e1f2"), 1, 3)
+	board.orderMoves(moves, packMove(findMove(t, moves, "d1d3")), 1)
+
+	// hash move, captures by victim then attacker, killer, the rest
+	expected := []string{"d1d3", "e4d5", "d1d5", "d1a4", "e1f2"}
+	if got := uciMoves(moves[:len(expected)]); !slices.Equal(got, expected) {
+		t.Fatalf("expected moves to start %v, got %v", expected, got)
+	}
+	if len(moves) != count {
+		t.Fatalf("ordering changed the number of moves from %d to %d", count, len(moves))
+	}
+}
+
+func TestOrderMovesPromotions(t *testing.T) {
+	board := searchBoard(t, "1r2k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	moves := board.GetAllValidMoves(WHITE)
+	board.orderMoves(moves, ttMove{}, 0)
+
+	expected := []string{"a7b8q", "a7a8q"}
+	if got := uciMoves(moves[:len(expected)]); !slices.Equal(got, expected) {
+		t.Fatalf("expected moves to start %v, got %v", expected, got)
+	}
+	// under-promotions, even capturing ones, come after the king moves
+	for _, move := range moves[len(moves)-6:] {
+		if !move.IsPromotion() || move.promotionType() == QUEEN {
+			t.Fatalf("expected under-promotions last, got %v", uciMoves(moves))
+		}
+	}
+}
+
+func TestRecordCutoff(t *testing.T) {
+	board := searchBoard(t, "4k3/8/8/3q4/p3P3/8/8/3QK3 w - - 0 1")
+	moves := board.GetAllValidMoves(WHITE)
+	state := &searchState{}
+	board.search = state
+
+	state.recordCutoff(findMove(t, moves, "e4d5"), 2, 4)
+	if len(state.killers) != 0 {
+		t.Fatalf("expected a capture not to be a killer")
+	}
+
+	first, second := findMove(t, moves, "e1f2"), findMove(t, moves, "e4e5")
+	state.recordCutoff(first, 2, 4)
+	state.recordCutoff(second, 2, 4)
+	state.recordCutoff(second, 2, 4)
+	if state.killers[2] != [2]ttMove{packMove(second), packMove(first)} {
+		t.Fatalf("expected the last two different killers, got %v", state.killers[2])
+	}
+	if score := board.orderScore(second, ttMove{}, 3); score != 32 {
+		t.Fatalf("expected a history score of 32 at another ply, got %d", score)
+	}
+
+	state.history[colorIndex(WHITE)][squareIndex(first.From)][squareIndex(first.To)] = HISTORY_MAX
+	state.recordCutoff(first, 0, 1)
+	if score := board.orderScore(second, ttMove{}, 3); score != 16 {
+		t.Fatalf("expected the history to be halved, got %d", score)
+	}
+}
@@ -0,0 +1,236 @@
+package board
+
+// Pawn structure scores, in hundredths of a pawn for the middlegame and
+// the endgame, blended by phase like the piece-square tables.
+const (
+	DOUBLED_MG, DOUBLED_EG   = -10, -20
+	ISOLATED_MG, ISOLATED_EG = -10, -15
+	BACKWARD_MG, BACKWARD_EG = -8, -10
+	// a pawn defended by a pawn is a link in a chain
+	CHAIN_MG, CHAIN_EG = 5, 3
+	// MAJORITY_EG is given to a side with more pawns than the other on a
+	// wing, where it can make a passed pawn.
+	MAJORITY_EG = 10
+	// PASSED_KING_EG weighs how much nearer the enemy king is to a passed
+	// pawn's path than the own king, times how far the pawn has come.
+	PASSED_KING_EG = 5
+
+	// PAWN_TABLE_SIZE is the number of entries in a board's pawn hash.
+	PAWN_TABLE_SIZE = 1 << 12
+)
+
+// Bonuses for a passed pawn by its rank, counted from its own side.
+var (
+	passedMG = [8]int{0, 5, 10, 15, 25, 40, 60, 0}
+	passedEG = [8]int{0, 10, 15, 25, 45, 70, 110, 0}
+)
+
+var (
+	fileMasks     [8]Bitboard
+	adjacentFiles [8]Bitboard
+	// frontSpans holds the squares ahead of a pawn on its file, and
+	// passedSpans those on its file and the files beside it.
+	frontSpans  [2][64]Bitboard
+	passedSpans [2][64]Bitboard
+	// supportSpans holds the squares on the files beside a pawn that are
+	// level with it or behind it, where pawns that can defend it stand or
+	// can come from.
+	supportSpans [2][64]Bitboard
+	queenside    Bitboard
+)
+
+func init() {
+	for col := 0; col < 8; col++ {
+		for row := 0; row < 8; row++ {
+			fileMasks[col] |= squareBit(row*8 + col)
+		}
+	}
+	for col := 0; col < 8; col++ {
+		if col > 0 {
+			adjacentFiles[col] |= fileMasks[col-1]
+		}
+		if col < 7 {
+			adjacentFiles[col] |= fileMasks[col+1]
+		}
+		if col < 4 {
+			queenside |= fileMasks[col]
+		}
+	}
+	for idx := 0; idx < 64; idx++ {
+		row, col := idx/8, idx%8
+		for r := 0; r < 8; r++ {
+			rank := rankMask(r)
+			if r < row {
+				frontSpans[WHITE_INDEX][idx] |= rank & fileMasks[col]
+				passedSpans[WHITE_INDEX][idx] |= rank & (fileMasks[col] | adjacentFiles[col])
+			} else {
+				supportSpans[WHITE_INDEX][idx] |= rank & adjacentFiles[col]
+			}
+			if r > row {
+				frontSpans[BLACK_INDEX][idx] |= rank & fileMasks[col]
+				passedSpans[BLACK_INDEX][idx] |= rank & (fileMasks[col] | adjacentFiles[col])
+			} else {
+				supportSpans[BLACK_INDEX][idx] |= rank & adjacentFiles[col]
+			}
+		}
+	}
+}
+
+// relativeRank returns the rank of idx counted from color's side, 0 for
+// its first rank.
+func relativeRank(color, idx int) int {
+	if color == WHITE_INDEX {
+		return 7 - idx/8
+	}
+	return idx / 8
+}
+
+// forward returns the square in front of idx for a pawn of color.
+func forward(color, idx int) int {
+	if color == WHITE_INDEX {
+		return idx - 8
+	}
+	return idx + 8
+}
+
+func squareDistance(a, b int) int {
+	return max(abs(a/8-b/8), abs(a%8-b%8))
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// pawnEntry is the pawn structure of a placement of the pawns, scored from
+// white's side.
+type pawnEntry struct {
+	key    uint64
+	mg, eg int
+	passed [2]Bitboard
+}
+
+// pawnTable caches pawn structures by pawnKey. Pawns move rarely, so most
+// positions a search visits share their pawns with one already scored.
+// An empty slot reads as the key of no pawns, which scores nothing, so it
+// needs no mark of its own.
+type pawnTable struct {
+	entries      []pawnEntry
+	probes, hits uint64
+}
+
+func newPawnTable() *pawnTable {
+	return &pawnTable{entries: make([]pawnEntry, PAWN_TABLE_SIZE)}
+}
+
+// pawnKey returns the Zobrist key of the pawns alone.
+func (b *Board) pawnKey() uint64 {
+	key := uint64(0)
+	for color := range b.bb.pieces {
+		for bb := b.bb.pieces[color][PAWN_INDEX]; bb != 0; bb &= bb - 1 {
+			key ^= zobristPieces[color][PAWN_INDEX][bb.first()]
+		}
+	}
+	return key
+}
+
+// pawnStructure returns the scored structure of the pawns on the board,
+// from the board's pawn hash when it has been seen before.
+func (b *Board) pawnStructure() pawnEntry {
+	if b.pawns == nil {
+		b.pawns = newPawnTable()
+	}
+	key := b.pawnKey()
+	slot := key & (PAWN_TABLE_SIZE - 1)
+	b.pawns.probes++
+	if b.pawns.entries[slot].key == key {
+		b.pawns.hits++
+		return b.pawns.entries[slot]
+	}
+	entry := b.scorePawns()
+	entry.key = key
+	b.pawns.entries[slot] = entry
+	return entry
+}
+
+// scorePawns scores doubled, isolated, backward, defended and passed pawns
+// and pawn majorities.
+func (b *Board) scorePawns() pawnEntry {
+	entry := pawnEntry{}
+	for color, sign := range [2]int{1, -1} {
+		own := b.bb.pieces[color][PAWN_INDEX]
+		enemy := b.bb.pieces[1-color][PAWN_INDEX]
+		mg, eg := 0, 0
+		for bb := own; bb != 0; bb &= bb - 1 {
+			idx := bb.first()
+			col := idx % 8
+			if frontSpans[color][idx]&own != 0 {
+				mg += DOUBLED_MG
+				eg += DOUBLED_EG
+			}
+			if adjacentFiles[col]&own == 0 {
+				mg += ISOLATED_MG
+				eg += ISOLATED_EG
+			} else if supportSpans[color][idx]&own == 0 && pawnAttacks[color][forward(color, idx)]&enemy != 0 {
+				// no pawn can come up to defend it, and it can't
+				// advance without being taken
+				mg += BACKWARD_MG
+				eg += BACKWARD_EG
+			}
+			if pawnAttacks[1-color][idx]&own != 0 {
+				mg += CHAIN_MG
+				eg += CHAIN_EG
+			}
+			if passedSpans[color][idx]&enemy == 0 && frontSpans[color][idx]&own == 0 {
+				rank := relativeRank(color, idx)
+				mg += passedMG[rank]
+				eg += passedEG[rank]
+				entry.passed[color] |= squareBit(idx)
+			}
+		}
+		for _, wing := range []Bitboard{queenside, ^queenside} {
+			if (own & wing).Count() > (enemy & wing).Count() {
+				eg += MAJORITY_EG
+			}
+		}
+		entry.mg += sign * mg
+		entry.eg += sign * eg
+	}
+	return entry
+}
+
+// passedPawnKingScore scores the kings' distances to the passed pawns'
+// paths: a passed pawn the enemy king is far from, and the own king near,
+// is worth more the further it has come. It is kept out of the pawn hash
+// since the kings move.
+func (b *Board) passedPawnKingScore(passed [2]Bitboard) int {
+	score := 0
+	for color, sign := range [2]int{1, -1} {
+		own, enemy := b.kingOf(color), b.kingOf(1-color)
+		if own == nil || enemy == nil {
+			continue
+		}
+		ownIdx, enemyIdx := squareIndex(own.Square()), squareIndex(enemy.Square())
+		for bb := passed[color]; bb != 0; bb &= bb - 1 {
+			idx := bb.first()
+			weight := relativeRank(color, idx) - 2
+			if weight <= 0 {
+				continue
+			}
+			stop := forward(color, idx)
+			score += sign * weight * PASSED_KING_EG *
+				(squareDistance(enemyIdx, stop) - squareDistance(ownIdx, stop)/2)
+		}
+	}
+	return score
+}
+
+// pawnStructureScore returns the pawn structure of white less that of
+// black, blended between the middlegame and endgame scores by the phase.
+func (b *Board) pawnStructureScore() float64 {
+	entry := b.pawnStructure()
+	eg := entry.eg + b.passedPawnKingScore(entry.passed)
+	return taper(entry.mg, eg, b.phase())
+}
@@ -0,0 +1,134 @@
+package board
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScorePawns(t *testing.T) {
+	tests := []struct {
+		name   string
+		fen    string
+		mg, eg int
+	}{
+		{"no pawns", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", 0, 0},
+		{
+			"isolated passed pawn",
+			"4k3/8/8/8/3P4/8/8/4K3 w - - 0 1",
+			ISOLATED_MG + passedMG[3], ISOLATED_EG + passedEG[3] + MAJORITY_EG,
+		},
+		{
+			"doubled isolated pawns",
+			"4k3/8/8/8/8/2P5/2P5/4K3 w - - 0 1",
+			2*ISOLATED_MG + DOUBLED_MG + passedMG[2],
+			2*ISOLATED_EG + DOUBLED_EG + passedEG[2] + MAJORITY_EG,
+		},
+		{
+			"chain",
+			"4k3/8/8/8/8/1P6/P7/4K3 w - - 0 1",
+			CHAIN_MG + passedMG[1] + passedMG[2],
+			CHAIN_EG + passedEG[1] + passedEG[2] + MAJORITY_EG,
+		},
+		{
+			// d3 can't be defended and c5 stops it
+			"backward pawn",
+			"4k3/8/8/2p5/4P3/3P4/8/4K3 w - - 0 1",
+			BACKWARD_MG + CHAIN_MG + passedMG[3] - ISOLATED_MG,
+			BACKWARD_EG + CHAIN_EG + passedEG[3] + MAJORITY_EG - ISOLATED_EG,
+		},
+	}
+	for _, tt := range tests {
+		board := searchBoard(t, tt.fen)
+		entry := board.scorePawns()
+		if entry.mg != tt.mg || entry.eg != tt.eg {
+			t.Errorf("%s: expected %d/%d, got %d/%d", tt.name, tt.mg, tt.eg, entry.mg, entry.eg)
+		}
+	}
+}
+
+func TestPassedPawns(t *testing.T) {
+	board := searchBoard(t, "4k3/p7/8/1P1p4/8/8/6P1/4K3 w - - 0 1")
+	entry := board.scorePawns()
+	if entry.passed[WHITE_INDEX] != squareBit(54) {
+		t.Errorf("expected g2 to be white's only passed pawn, got %d squares", entry.passed[WHITE_INDEX].Count())
+	}
+	if entry.passed[BLACK_INDEX] != squareBit(27) {
+		t.Errorf("expected d5 to be black's only passed pawn, got %d squares", entry.passed[BLACK_INDEX].Count())
+	}
+}
+
+func TestPassedPawnKingScore(t *testing.T) {
+	tests := []struct {
+		name          string
+		better, worse string
+	}{
+		{
+			"enemy king far from the passed pawn",
+			"7k/8/2P5/8/8/8/8/2K5 w - - 0 1",
+			"2k5/8/2P5/8/8/8/8/7K w - - 0 1",
+		},
+		{
+			"own king escorts the passed pawn",
+			"7k/8/2P5/2K5/8/8/8/8 w - - 0 1",
+			"7k/8/2P5/8/8/8/8/6K1 w - - 0 1",
+		},
+		{
+			"further advanced passed pawn",
+			"7k/2P5/8/8/8/8/8/2K5 w - - 0 1",
+			"7k/8/8/8/2P5/8/8/2K5 w - - 0 1",
+		},
+	}
+	for _, tt := range tests {
+		better, worse := searchBoard(t, tt.better), searchBoard(t, tt.worse)
+		if better.pawnStructureScore() <= worse.pawnStructureScore() {
+			t.Errorf("%s: expected %f to beat %f", tt.name, better.pawnStructureScore(), worse.pawnStructureScore())
+		}
+	}
+}
+
+func TestPawnStructureSymmetry(t *testing.T) {
+	white := searchBoard(t, "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1")
+	black := searchBoard(t, "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1")
+	if math.Abs(white.pawnStructureScore()+black.pawnStructureScore()) > 1e-9 {
+		t.Fatalf("mirrored positions score %f and %f", white.pawnStructureScore(), black.pawnStructureScore())
+	}
+}
+
+func TestPawnHash(t *testing.T) {
+	board := searchBoard(t, "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 0 1")
+	key := board.pawnKey()
+	score := board.pawnStructureScore()
+
+	// a bishop move leaves the pawns, and their key, as they were
+	move, err := board.ParseUci("f1c4")
+	if err != nil {
+		t.Fatalf("ParseUci returned error: %s", err.Message)
+	}
+	board.MovePiece(move)
+	board.Evaluate(WHITE)
+	if board.pawnKey() != key {
+		t.Fatalf("expected a bishop move to keep the pawn key")
+	}
+	hits := board.pawns.hits
+	if board.pawnStructureScore() != score {
+		t.Fatalf("expected the cached pawn structure to score %f", score)
+	}
+	if board.pawns.hits != hits+1 {
+		t.Fatalf("expected the pawn structure to come from the pawn hash")
+	}
+	board.UndoMove()
+
+	move, err = board.ParseUci("d2d4")
+	if err != nil {
+		t.Fatalf("ParseUci returned error: %s", err.Message)
+	}
+	board.MovePiece(move)
+	board.Evaluate(WHITE)
+	if board.pawnKey() == key {
+		t.Fatalf("expected a pawn move to change the pawn key")
+	}
+
+	if copied := board.Copy(); copied.pawns != nil {
+		t.Fatalf("expected a copy to start without the pawn hash")
+	}
+}
@@ -0,0 +1,43 @@
+package board
+
+// Perft counts the positions reached by playing every legal move sequence
+// depth plies deep from the current position. Comparing the count against
+// published values for well-known positions checks the move generator.
+func (b *Board) Perft(depth int) uint64 {
+	b.Evaluate(ENEMY[b.Turn])
+	return b.perft(depth)
+}
+
+// Divide runs Perft below each legal move of the side to move and returns
+// the counts keyed by the move in UCI notation.
+func (b *Board) Divide(depth int) map[string]uint64 {
+	b.Evaluate(ENEMY[b.Turn])
+	counts := map[string]uint64{}
+	if depth < 1 {
+		return counts
+	}
+	for _, move := range b.GetAllValidMoves(b.Turn) {
+		b.MovePiece(move)
+		counts[move.Uci()] = b.perft(depth - 1)
+		b.UndoMove()
+	}
+	return counts
+}
+
+// perft expects the board to have been evaluated for the side to move.
+func (b *Board) perft(depth int) uint64 {
+	if depth < 1 {
+		return 1
+	}
+	moves := b.GetAllValidMoves(b.Turn)
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	nodes := uint64(0)
+	for _, move := range moves {
+		b.MovePiece(move)
+		nodes += b.perft(depth - 1)
+		b.UndoMove()
+	}
+	return nodes
+}
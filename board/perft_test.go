@@ -0,0 +1,99 @@
+package board
+
+import "testing"
+
+// Node counts for the standard perft positions, see
+// https://www.chessprogramming.org/Perft_Results
+var perftTests = []struct {
+	name   string
+	fen    string
+	counts []uint64
+}{
+	{
+		"start position",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		[]uint64{20, 400, 8902, 197281},
+	},
+	{
+		"kiwipete",
+		kiwipeteFen,
+		[]uint64{48, 2039, 97862},
+	},
+	{
+		"position 3",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		[]uint64{14, 191, 2812, 43238},
+	},
+	{
+		"position 4",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		[]uint64{6, 264, 9467},
+	},
+	{
+		"position 4 mirrored",
+		"r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1",
+		[]uint64{6, 264, 9467},
+	},
+	{
+		"position 5",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		[]uint64{44, 1486, 62379},
+	},
+	{
+		"position 6",
+		"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+		[]uint64{46, 2079, 89890},
+	},
+}
+
+func TestPerft(t *testing.T) {
+	for _, tt := range perftTests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := New()
+			if err := board.SetupFromFen(tt.fen); err != nil {
+				t.Fatalf("SetupFromFen returned error: %s", err.Message)
+			}
+			for i, expected := range tt.counts {
+				depth := i + 1
+				if nodes := board.Perft(depth); nodes != expected {
+					t.Errorf("Perft(%d) = %d, expected %d", depth, nodes, expected)
+				}
+			}
+			if fen := board.Fen(); fen != tt.fen {
+				t.Errorf("board not restored after Perft: %s", fen)
+			}
+		})
+	}
+}
+
+func TestDivide(t *testing.T) {
+	board := New()
+	if err := board.SetupFromFen(kiwipeteFen); err != nil {
+		t.Fatalf("SetupFromFen returned error: %s", err.Message)
+	}
+	counts := board.Divide(2)
+	if len(counts) != 48 {
+		t.Fatalf("expected 48 moves, got %d", len(counts))
+	}
+	total := uint64(0)
+	for _, nodes := range counts {
+		total += nodes
+	}
+	if total != 2039 {
+		t.Errorf("expected divide counts to sum to 2039, got %d", total)
+	}
+	// castling both ways, and the pawn moves that can be answered by an
+	// en passant capture
+	expected := map[string]uint64{
+		"e1g1": 43,
+		"e1c1": 43,
+		"a2a4": 44,
+		"g2g4": 42,
+		"d5e6": 46,
+	}
+	for uci, nodes := range expected {
+		if counts[uci] != nodes {
+			t.Errorf("%s: expected %d, got %d", uci, nodes, counts[uci])
+		}
+	}
+}
@@ -27,7 +27,7 @@ func TestPieceStopsCheck(t *testing.T) {
 		From:  board2.Squares[ROW_7][COL_F],
 		To:    board2.Squares[ROW_8][COL_G],
 	}
-	board2.MovePiece(move)
+	playMove(board2, move)
 	board2.Evaluate(WHITE)
 
 	tests := []struct {
@@ -55,7 +55,7 @@ func TestPieceStopsCheck(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 		if receipt != tt.expected {
 			t.Fatalf("Receipt should be %s. Got %s", tt.expected, receipt)
 		}
@@ -405,7 +405,7 @@ func TestPieceBlocksCheck(t *testing.T) {
 
 	for _, tt := range tests {
 		tt.board.Evaluate(ENEMY[tt.input.Turn])
-		receipt, err := tt.board.MovePiece(tt.input)
+		receipt, err := playMove(tt.board, tt.input)
 		if receipt != tt.expected {
 			t.Fatalf("Receipt should be %s. Got %s", tt.expected, receipt)
 		}
@@ -530,7 +530,7 @@ func TestAbsolutePins(t *testing.T) {
 
 	for _, tt := range tests {
 		tt.board.Evaluate(ENEMY[tt.input.Turn])
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("Receipt should be '%s'. Got '%s'", tt.expected, receipt)
@@ -548,105 +548,156 @@ func TestAbsolutePins(t *testing.T) {
 
 }
 
-/*func TestPawnPromotion(t *testing.T) {
-board := New()
-
-c7 := board.Squares[ROW_7][COL_C]
-e7 := board.Squares[ROW_7][COL_E]
-d2 := board.Squares[ROW_2][COL_D]
-f2 := board.Squares[ROW_2][COL_F]
-b8 := board.Squares[ROW_8][COL_B]
-g1 := board.Squares[ROW_1][COL_G]
-h7 := board.Squares[ROW_7][COL_H]
-a2 := board.Squares[ROW_2][COL_A]
-
-whiteEPawn := &Pawn{color: WHITE, Moved: true}
-whiteCPawn := &Pawn{color: WHITE, Moved: true}
-blackDPawn := &Pawn{color: BLACK, Moved: true}
-blackFPawn := &Pawn{color: BLACK, Moved: true}
-blackQueen := &Queen{color: BLACK}
-whiteBishop := &Bishop{color: WHITE}
-whiteKing := &King{color: WHITE}
-blackKing := &King{color: BLACK}
-
-board.SetPiece(whiteEPawn, e7)
-board.SetPiece(whiteCPawn, c7)
-board.SetPiece(blackDPawn, d2)
-board.SetPiece(blackFPawn, f2)
-board.SetPiece(blackQueen, b8)
-board.SetPiece(whiteBishop, g1)
-board.SetPiece(whiteKing, h7)
-board.SetPiece(blackKing, a2)
-
-tests := []struct {
-	input             *Move
-	expectedReceipt   string
-	expectedPromotion string
-}{
-	{
-		&Move{
-			Turn:      WHITE,
-			Piece:     whiteEPawn,
-			From:      e7,
-			To:        board.Squares[ROW_8][COL_E],
-			Promotion: &Queen{color: WHITE},
-		},
-		"PAWN: E7 -> E8 (PROMOTION: QUEEN)",
-		QUEEN,
-	},
-	{
-		&Move{
-			Turn:      BLACK,
-			Piece:     blackDPawn,
-			From:      d2,
-			To:        board.Squares[ROW_1][COL_D],
-			Promotion: &Knight{color: BLACK},
-		},
-		"PAWN: D2 -> D1 (PROMOTION: KNIGHT)",
-		KNIGHT,
-	},
-	{
-		&Move{
-			Turn:      WHITE,
-			Piece:     whiteCPawn,
-			From:      c7,
-			To:        b8,
-			Promotion: &Bishop{color: WHITE},
-		},
-		"PAWN TAKES QUEEN: C7 -> B8 (PROMOTION: BISHOP)",
-		BISHOP,
-	},
-	{
-		&Move{
-			Turn:      BLACK,
-			Piece:     blackFPawn,
-			From:      f2,
-			To:        g1,
-			Promotion: &Rook{color: BLACK},
-		},
-		"PAWN TAKES BISHOP: F2 -> G1 (PROMOTION: ROOK)",
-		ROOK,
-	},
+func TestPawnPromotion(t *testing.T) {
+	board := New()
+
+	c7 := board.Squares[ROW_7][COL_C]
+	e7 := board.Squares[ROW_7][COL_E]
+	d2 := board.Squares[ROW_2][COL_D]
+	f2 := board.Squares[ROW_2][COL_F]
+	b8 := board.Squares[ROW_8][COL_B]
+	g1 := board.Squares[ROW_1][COL_G]
+	h7 := board.Squares[ROW_7][COL_H]
+	a3 := board.Squares[ROW_3][COL_A]
+
+	whiteEPawn := &Pawn{color: WHITE, moveCount: 1}
+	whiteCPawn := &Pawn{color: WHITE, moveCount: 1}
+	blackDPawn := &Pawn{color: BLACK, moveCount: 1}
+	blackFPawn := &Pawn{color: BLACK, moveCount: 1}
+	blackQueen := &Queen{color: BLACK}
+	whiteBishop := &Bishop{color: WHITE}
+	whiteKing := &King{color: WHITE}
+	blackKing := &King{color: BLACK}
+
+	board.SetPiece(whiteEPawn, e7)
+	board.SetPiece(whiteCPawn, c7)
+	board.SetPiece(blackDPawn, d2)
+	board.SetPiece(blackFPawn, f2)
+	board.SetPiece(blackQueen, b8)
+	board.SetPiece(whiteBishop, g1)
+	board.SetPiece(whiteKing, h7)
+	board.SetPiece(blackKing, a3)
+
+	tests := []struct {
+		input             *Move
+		expectedReceipt   string
+		expectedPromotion string
+	}{
+		{
+			&Move{
+				Turn:      WHITE,
+				Piece:     whiteEPawn,
+				From:      e7,
+				To:        board.Squares[ROW_8][COL_E],
+				Promotion: &Queen{color: WHITE},
+			},
+			"PAWN: E7 -> E8 (PROMOTION: QUEEN)",
+			QUEEN,
+		},
+		{
+			&Move{
+				Turn:      BLACK,
+				Piece:     blackDPawn,
+				From:      d2,
+				To:        board.Squares[ROW_1][COL_D],
+				Promotion: &Knight{color: BLACK},
+			},
+			"PAWN: D2 -> D1 (PROMOTION: KNIGHT)",
+			KNIGHT,
+		},
+		{
+			&Move{
+				Turn:      WHITE,
+				Piece:     whiteCPawn,
+				From:      c7,
+				To:        b8,
+				Promotion: &Bishop{color: WHITE},
+			},
+			"PAWN TAKES QUEEN: C7 -> B8 (PROMOTION: BISHOP)",
+			BISHOP,
+		},
+		{
+			&Move{
+				Turn:      BLACK,
+				Piece:     blackFPawn,
+				From:      f2,
+				To:        g1,
+				Promotion: &Rook{color: BLACK},
+			},
+			"PAWN TAKES BISHOP: F2 -> G1 (PROMOTION: ROOK)",
+			ROOK,
+		},
+	}
+
+	for _, tt := range tests {
+		board.Evaluate(ENEMY[tt.input.Turn])
+		receipt, err := playMove(board, tt.input)
+		if err != nil {
+			t.Fatalf("Test case should not return error. Got '%s'", err.Message)
+		}
+		if receipt != tt.expectedReceipt {
+			t.Fatalf("Receipt should be '%s'. Got '%s'", tt.expectedReceipt, receipt)
+		}
+		if tt.input.From.Piece.Type() != NULL {
+			t.Fatalf("Square %s should be NULL. Got %s", tt.input.From.Name, tt.input.From.Piece.Type())
+		}
+		promoted := tt.input.To.Piece
+		if promoted.Type() != tt.expectedPromotion {
+			t.Fatalf("Square %s should now have %s. Got %s", tt.input.To.Name, tt.expectedPromotion, promoted.Type())
+		}
+		if promoted.Color() != tt.input.Turn || promoted.Value() != board.CreatePiece(tt.input.Turn, tt.expectedPromotion).Value() {
+			t.Fatalf("%s should be a %s piece worth its full value. Got %s worth %f", promoted.Type(), tt.input.Turn, promoted.Color(), promoted.Value())
+		}
+	}
 }
 
-for _, tt := range tests {
-	board.Evaluate(tt.input.Turn)
-	receipt, err := board.MovePiece(tt.input)
-	if err != nil {
-		t.Fatalf("Test case should not return error")
+func TestUnderPromotionMoves(t *testing.T) {
+	board := New()
+	board.SetupFromFen("1r5k/2P5/8/8/8/8/8/4K3 w - - 0 1")
+	board.Evaluate(BLACK)
+
+	promotions := map[string]int{}
+	for _, move := range board.GetAllValidMoves(WHITE) {
+		if move.IsPromotion() {
+			promotions[move.Uci()]++
+		}
+	}
+	for _, uci := range []string{"c7c8q", "c7c8r", "c7c8b", "c7c8n", "c7b8q", "c7b8r", "c7b8b", "c7b8n"} {
+		if promotions[uci] != 1 {
+			t.Fatalf("%s should be generated once. Got %v", uci, promotions)
+		}
 	}
-	if receipt != tt.expectedReceipt {
-		t.Fatalf("Receipt should be '%s'. Got '%s'", tt.expectedReceipt, receipt)
+	if len(promotions) != 8 {
+		t.Fatalf("there should be 8 promotion moves. Got %v", promotions)
 	}
-	if tt.input.From.Piece.Type() != NULL {
-		t.Fatalf("Square %s should be NULL. Got %s", tt.input.From.Name, tt.input.From.Piece.Type())
+
+	move, _ := board.ParseUci("c7b8n")
+	playMove(board, move)
+	if board.Fen() != "1N5k/8/8/8/8/8/8/4K3 b - - 0 1" {
+		t.Fatalf("c7b8n should promote to a knight. Got %s", board.Fen())
 	}
-	if tt.input.To.Piece.Type() != tt.expectedPromotion {
-		t.Fatalf("Square %s should now have Queen. Got %s", tt.input.To.Name, tt.input.To.Piece.Type())
+	board.UndoMove()
+	if board.Fen() != "1r5k/2P5/8/8/8/8/8/4K3 w - - 0 1" {
+		t.Fatalf("undoing c7b8n should restore the pawn and rook. Got %s", board.Fen())
+	}
+	if len(board.WhitePieces) != 2 || len(board.BlackPieces) != 2 {
+		t.Fatalf("undo should restore the piece sets. Got %d white and %d black", len(board.WhitePieces), len(board.BlackPieces))
 	}
 }
 
-}*/
+func TestKnightPromotionMate(t *testing.T) {
+	board := New()
+	board.SetupFromFen("rqb5/pkpP4/ppp5/8/8/8/8/4K3 w - - 0 1")
+	board.Evaluate(BLACK)
+
+	move := board.BestMove(WHITE)
+	if move.Uci() != "d7d8n" {
+		t.Fatalf("best move should be d7d8n. Got %s", move.Uci())
+	}
+	if san := board.San(move); san != "d8=N#" {
+		t.Fatalf("knight promotion should be mate. Got %s", san)
+	}
+}
 
 func TestCastle(t *testing.T) {
 	shortBoard := New()
@@ -917,7 +968,7 @@ func TestCastle(t *testing.T) {
 	for _, tt := range tests {
 		ogRookSq := tt.rook.Square()
 		tt.board.Evaluate(ENEMY[tt.input.Turn])
-		receipt, err := tt.board.MovePiece(tt.input)
+		receipt, err := playMove(tt.board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be '%s'. Got '%s'", tt.expected, receipt)
@@ -1164,7 +1215,7 @@ func TestKingMove(t *testing.T) {
 			t.Fatalf("Piece should be a %s. Got %s", KING, tt.input.Piece.Type())
 		}
 
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be '%s'. Got '%s'", tt.expected, receipt)
@@ -1301,7 +1352,7 @@ func TestQueenMove(t *testing.T) {
 			t.Fatalf("Piece should be a %s. Got %s", QUEEN, tt.input.Piece.Type())
 		}
 
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 		board.Evaluate(tt.input.Turn)
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be '%s'. Got '%s'", tt.expected, receipt)
@@ -1399,7 +1450,7 @@ func TestMoveRook(t *testing.T) {
 			t.Fatalf("Piece should be a %s. Got %s", ROOK, tt.input.Piece.Type())
 		}
 
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be '%s'. Got '%s'", tt.expected, receipt)
@@ -1523,7 +1574,7 @@ func TestMoveBishop(t *testing.T) {
 			t.Fatalf("Piece should be a %s. Got %s", BISHOP, tt.input.Piece.Type())
 		}
 
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be '%s'. Got '%s'", tt.expected, receipt)
@@ -1628,7 +1679,7 @@ func TestMoveKnight(t *testing.T) {
 			t.Fatalf("Piece should be a %s. Got %s", KNIGHT, tt.input.Piece.Type())
 		}
 
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be '%s'. Got '%s'", tt.expected, receipt)
@@ -1738,7 +1789,7 @@ func TestFreeMovePawn(t *testing.T) {
 		if tt.input.Piece.Type() != PAWN {
 			t.Fatalf("Piece should be a %s. Got %s", PAWN, tt.input.Piece.Type())
 		}
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be: '%s'. Got %s", tt.expected, receipt)
@@ -1837,7 +1888,7 @@ func TestCaptureMovePawn(t *testing.T) {
 		if tt.input.Piece.Type() != PAWN {
 			t.Fatalf("Piece should be a %s. Got %s", PAWN, tt.input.Piece.Type())
 		}
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be: '%s'. Got %s", tt.expected, receipt)
@@ -1917,7 +1968,7 @@ func TestEnPassantAsBlack(t *testing.T) {
 		if tt.input.Piece.Type() != PAWN {
 			t.Fatalf("Piece should be a %s. Got %s", PAWN, tt.input.Piece.Type())
 		}
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be: '%s'. Got %s", tt.expected, receipt)
@@ -1998,7 +2049,7 @@ func TestEnPassantAsWhite(t *testing.T) {
 		if tt.input.Piece.Type() != PAWN {
 			t.Fatalf("Piece should be a %s. Got %s", PAWN, tt.input.Piece.Type())
 		}
-		receipt, err := board.MovePiece(tt.input)
+		receipt, err := playMove(board, tt.input)
 
 		if receipt != tt.expected {
 			t.Fatalf("receipt should be: '%s'. Got %s", tt.expected, receipt)
@@ -2021,6 +2072,16 @@ func TestEnPassantAsWhite(t *testing.T) {
 	}
 }
 
+// playMove makes move whether or not it is its side's turn, passing the
+// turn to it first. The piece tests play out moves of either side on one
+// board.
+func playMove(b *Board, move *Move) (string, *Error) {
+	if move.Piece.Color() != b.Turn {
+		b.passTurn()
+	}
+	return b.MovePiece(move)
+}
+
 func testPieceHasMoved(piece Piece, fromSquare *Square, toSquare *Square) bool {
 	return fromSquare.IsEmpty() && toSquare.Piece == piece && piece.Square().Name == toSquare.Name
 }
@@ -2039,6 +2100,6 @@ func testSquareOccupiedByPiece(square *Square, piece Piece) bool {
 
 func moveNonTargetPiece(piece Piece, from *Square, to *Square, board *Board) {
 	move := &Move{Piece: piece, From: from, To: to}
-	board.MovePiece(move)
+	playMove(board, move)
 	board.Evaluate(piece.Color())
 }
@@ -0,0 +1,155 @@
+package board
+
+// Piece-square tables give every piece a bonus or penalty, in hundredths of
+// a pawn, for the square it stands on. Each kind has one table for the
+// middlegame and one for the endgame, and Evaluate blends the two by how
+// much material is left. Tables are laid out as the board is seen by
+// white, with the eighth rank first, so a square's index into them is its
+// bit index; black pieces look them up on the mirrored square.
+
+// TOTAL_PHASE is the game phase of a position with all its pieces: each
+// knight and bishop counts one, each rook two and each queen four.
+const TOTAL_PHASE = 24
+
+var phaseWeights = [6]int{0, 1, 1, 2, 4, 0}
+
+var (
+	pawnMG = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	// in the endgame a pawn is worth more the nearer it is to promoting
+	pawnEG = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		60, 60, 60, 60, 60, 60, 60, 60,
+		40, 40, 40, 40, 40, 40, 40, 40,
+		25, 25, 25, 25, 25, 25, 25, 25,
+		15, 15, 15, 15, 15, 15, 15, 15,
+		5, 5, 5, 5, 5, 5, 5, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	knightPST = [64]int{
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	}
+	bishopPST = [64]int{
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	}
+	rookMG = [64]int{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		0, 0, 0, 5, 5, 0, 0, 0,
+	}
+	// an endgame rook is good anywhere but best on the seventh rank
+	rookEG = [64]int{
+		5, 5, 5, 5, 5, 5, 5, 5,
+		15, 15, 15, 15, 15, 15, 15, 15,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	queenPST = [64]int{
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	}
+	// the middlegame king hides behind its pawns
+	kingMG = [64]int{
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		20, 30, 10, 0, 0, 10, 30, 20,
+	}
+	// the endgame king comes to the centre
+	kingEG = [64]int{
+		-50, -40, -30, -20, -20, -30, -40, -50,
+		-30, -20, -10, 0, 0, -10, -20, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 30, 40, 40, 30, -10, -30,
+		-30, -10, 20, 30, 30, 20, -10, -30,
+		-30, -30, 0, 0, 0, 0, -30, -30,
+		-50, -30, -30, -30, -30, -30, -30, -50,
+	}
+)
+
+// pstMG and pstEG hold the tables by kindIndex. Minor pieces and queens use
+// the same table in both.
+var (
+	pstMG = [6]*[64]int{&pawnMG, &knightPST, &bishopPST, &rookMG, &queenPST, &kingMG}
+	pstEG = [6]*[64]int{&pawnEG, &knightPST, &bishopPST, &rookEG, &queenPST, &kingEG}
+)
+
+// phase returns the game phase from the pieces left, TOTAL_PHASE with all
+// of them and 0 with only kings and pawns.
+func (b *Board) phase() int {
+	phase := 0
+	for color := range b.bb.pieces {
+		for kind, weight := range phaseWeights {
+			phase += weight * b.bb.pieces[color][kind].Count()
+		}
+	}
+	return min(phase, TOTAL_PHASE)
+}
+
+// pieceSquareScore returns the piece-square bonuses of white less those of
+// black, blended between the middlegame and endgame tables by the phase.
+func (b *Board) pieceSquareScore() float64 {
+	mg, eg := 0, 0
+	for color, sign := range [2]int{1, -1} {
+		for kind := range b.bb.pieces[color] {
+			for bb := b.bb.pieces[color][kind]; bb != 0; bb &= bb - 1 {
+				idx := bb.first()
+				if color == BLACK_INDEX {
+					idx ^= 56
+				}
+				mg += sign * pstMG[kind][idx]
+				eg += sign * pstEG[kind][idx]
+			}
+		}
+	}
+	return taper(mg, eg, b.phase())
+}
+
+// taper blends middlegame and endgame scores in hundredths of a pawn by
+// phase and returns the result in pawns.
+func taper(mg, eg, phase int) float64 {
+	return float64(mg*phase+eg*(TOTAL_PHASE-phase)) / TOTAL_PHASE / 100
+}
@@ -0,0 +1,62 @@
+package board
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPhase(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected int
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", TOTAL_PHASE},
+		{"4k3/pppp4/8/8/8/8/PPPP4/4K3 w - - 0 1", 0},
+		{"3qk3/8/8/8/8/8/8/2R1K3 w - - 0 1", 6},
+		// promoted queens don't take the phase past the start
+		{"QQQQkQQQ/8/8/8/8/8/8/4K3 w - - 0 1", TOTAL_PHASE},
+	}
+	for _, tt := range tests {
+		board := searchBoard(t, tt.fen)
+		if phase := board.phase(); phase != tt.expected {
+			t.Errorf("%s: expected phase %d, got %d", tt.fen, tt.expected, phase)
+		}
+	}
+}
+
+func TestPieceSquareSymmetry(t *testing.T) {
+	white := searchBoard(t, "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1")
+	black := searchBoard(t, "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1")
+	if math.Abs(white.pieceSquareScore()+black.pieceSquareScore()) > 1e-9 {
+		t.Fatalf("mirrored positions score %f and %f", white.pieceSquareScore(), black.pieceSquareScore())
+	}
+}
+
+func TestPieceSquareScore(t *testing.T) {
+	tests := []struct {
+		name          string
+		better, worse string
+	}{
+		{
+			"king comes to the centre in the endgame",
+			"4k3/pppp4/8/8/8/4K3/PPPP4/8 w - - 0 1",
+			"4k3/pppp4/8/8/8/8/PPPP4/7K w - - 0 1",
+		},
+		{
+			"king stays home in the middlegame",
+			"r1bqkbnr/pppppppp/2n5/8/8/5N2/PPPPPPPP/RNBQ1RK1 w kq - 0 1",
+			"r1bqkbnr/pppppppp/2n5/8/8/4KN2/PPPPPPPP/RNBQ1R2 w kq - 0 1",
+		},
+		{
+			"knight in the centre, not on the rim",
+			"rnbqkbnr/pppppppp/8/8/4N3/8/PPPPPPPP/R1BQKBNR w KQkq - 0 1",
+			"rnbqkbnr/pppppppp/8/8/N7/8/PPPPPPPP/R1BQKBNR w KQkq - 0 1",
+		},
+	}
+	for _, tt := range tests {
+		better, worse := searchBoard(t, tt.better), searchBoard(t, tt.worse)
+		if better.pieceSquareScore() <= worse.pieceSquareScore() {
+			t.Errorf("%s: expected %f to beat %f", tt.name, better.pieceSquareScore(), worse.pieceSquareScore())
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package board
+
+import "math"
+
+// DELTA_MARGIN is added to the material a capture wins before delta pruning
+// decides it can't matter, to allow for positional gains.
+const DELTA_MARGIN = 2.0
+
+// Quiescence scores the position for turn by searching captures and queen
+// promotions until none are left, so that a search never stops in the
+// middle of an exchange. The side to move may instead "stand pat" on the
+// static evaluation, since it is not forced to capture. A side in check has
+// to answer it, so then every legal move is searched and there is no stand
+// pat. Otherwise captures that lose material by SEE are skipped. Scores are
+// from white's side, as in MiniMax, and the captures that lead to the score
+// extend the principal variation.
+func (b *Board) Quiescence(turn string, alpha float64, beta float64) float64 {
+	ply := b.search.ply(b)
+	b.search.clearPV(ply)
+	if b.search.visit() {
+		return 0.0
+	}
+	if b.Checkmate {
+		return mateScore(turn, ply)
+	}
+	if b.Stalemate || b.Draw {
+		return 0.0
+	}
+	if ply > 0 && b.ClaimableDraw() != "" {
+		// scored as drawn, since a side doing worse would claim it
+		return 0.0
+	}
+
+	inCheck := b.inCheck(turn)
+	standPat := b.Value
+	var moves []*Move
+	if inCheck {
+		moves = b.GetAllValidMoves(turn)
+	} else {
+		moves = b.generateMoves(turn, true)
+	}
+	b.orderMoves(moves, ttMove{}, ply)
+
+	if turn == WHITE {
+		best := math.Inf(-1)
+		if !inCheck {
+			best = standPat
+			if best >= beta {
+				return best
+			}
+			alpha = math.Max(alpha, best)
+		}
+		for _, move := range moves {
+			if !inCheck && (standPat+b.materialGain(move)+DELTA_MARGIN <= alpha || b.losesMaterial(move)) {
+				continue
+			}
+			b.MovePiece(move)
+			eval := b.Quiescence(BLACK, alpha, beta)
+			b.UndoMove()
+			if b.search.aborted() {
+				return 0.0
+			}
+			if eval > best {
+				best = eval
+				b.search.updatePV(ply, move)
+			}
+			alpha = math.Max(alpha, eval)
+			if alpha >= beta {
+				break
+			}
+		}
+		return best
+	}
+
+	best := math.Inf(1)
+	if !inCheck {
+		best = standPat
+		if best <= alpha {
+			return best
+		}
+		beta = math.Min(beta, best)
+	}
+	for _, move := range moves {
+		if !inCheck && (standPat-b.materialGain(move)-DELTA_MARGIN >= beta || b.losesMaterial(move)) {
+			continue
+		}
+		b.MovePiece(move)
+		eval := b.Quiescence(WHITE, alpha, beta)
+		b.UndoMove()
+		if b.search.aborted() {
+			return 0.0
+		}
+		if eval < best {
+			best = eval
+			b.search.updatePV(ply, move)
+		}
+		beta = math.Min(beta, eval)
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// materialGain returns the material move wins outright: the piece it takes
+// and what a promotion adds.
+func (b *Board) materialGain(move *Move) float64 {
+	gain := 0.0
+	if victim := move.To.Piece; victim.Type() != NULL {
+		gain += math.Abs(victim.Value())
+	} else if move.Piece.Type() == PAWN && move.From.Column != move.To.Column {
+		gain += PieceValues[PAWN]
+	}
+	if move.IsPromotion() {
+		gain += PieceValues[move.promotionType()] - PieceValues[PAWN]
+	}
+	return gain
+}
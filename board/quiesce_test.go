@@ -0,0 +1,61 @@
+package board
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestQuiescenceStandPat(t *testing.T) {
+	board := searchBoard(t, "4k3/8/8/8/8/8/3P4/4K3 w - - 0 1")
+	if score := board.Quiescence(WHITE, math.Inf(-1), math.Inf(1)); score != board.Value {
+		t.Fatalf("quiet position should score its evaluation %f, got %f", board.Value, score)
+	}
+}
+
+func TestQuiescenceResolvesCaptures(t *testing.T) {
+	// line is the exchange quiescence should expect, and the score should be
+	// about the evaluation at its end
+	tests := []struct {
+		name string
+		fen  string
+		line []string
+	}{
+		{"hanging queen", "4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", []string{"d2d5"}},
+		{"defended pawn", "4k3/8/2p5/3p4/8/8/3Q4/4K3 w - - 0 1", nil},
+		{"rook takes rook, pawn recaptures", "4k3/8/2p5/3r4/8/8/3R4/4K3 w - - 0 1", []string{"d2d5", "c6d5"}},
+		{"queen promotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", []string{"b7b8q"}},
+	}
+	for _, tt := range tests {
+		board := searchBoard(t, tt.fen)
+		for _, uci := range tt.line {
+			move, err := board.ParseUci(uci)
+			if err != nil {
+				t.Fatalf("%s: ParseUci returned error: %s", tt.name, err.Message)
+			}
+			board.MovePiece(move)
+		}
+		expected := board.Value
+		for range tt.line {
+			board.UndoMove()
+		}
+
+		score := board.Quiescence(WHITE, math.Inf(-1), math.Inf(1))
+		if math.Abs(score-expected) > 0.5 {
+			t.Errorf("%s: expected about %f, got %f", tt.name, expected, score)
+		}
+		if board.Fen() != tt.fen {
+			t.Errorf("%s: quiescence left the board at %s", tt.name, board.Fen())
+		}
+	}
+}
+
+func TestQuiescenceAvoidsHorizonBlunder(t *testing.T) {
+	// Qxd5 wins a pawn at the horizon of a one ply search, but cxd5 then
+	// takes the queen
+	board := searchBoard(t, "4k3/8/2p5/3p4/8/8/3Q4/4K3 w - - 0 1")
+	result := board.Search(context.Background(), WHITE, SearchLimits{Depth: 1})
+	if result.Move.Uci() == "d2d5" {
+		t.Fatalf("search played Qxd5 into cxd5")
+	}
+}
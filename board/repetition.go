@@ -0,0 +1,80 @@
+package board
+
+// PositionFen returns the first four FEN fields, which identify a position
+// for the repetition rules: placement, side to move, castling rights and the
+// en passant square. The en passant square is only included when the side to
+// move can legally capture on it.
+func (b *Board) PositionFen() string {
+	enPassant := "-"
+	if b.enPassantCapturable() {
+		enPassant = b.enPassantFen()
+	}
+	return b.PlacementFen() + " " + b.turnFen() + " " + b.castlingFen() + " " + enPassant
+}
+
+// RepetitionCount returns how many times the current position has occurred.
+// Board.Hashes holds the key of the position before every move played, and
+// only the positions since the last capture or pawn move are searched since
+// none of the earlier ones can occur again. Positions with the same side to
+// move are two plies apart, so every other key is compared.
+func (b *Board) RepetitionCount() int {
+	reversible := min(b.HalfmoveClock, len(b.Hashes))
+	if reversible < 4 {
+		return 1
+	}
+	count := 1
+	for i := len(b.Hashes) - 2; i >= len(b.Hashes)-reversible; i -= 2 {
+		if b.Hashes[i] == b.hash {
+			count++
+		}
+	}
+	return count
+}
+
+// DrawByRepetition reports whether the position has occurred three times, so
+// the side to move may claim a draw.
+func (b *Board) DrawByRepetition() bool {
+	return b.RepetitionCount() >= 3
+}
+
+// DrawByFivefoldRepetition reports whether the position has occurred five
+// times, which draws the game.
+func (b *Board) DrawByFivefoldRepetition() bool {
+	return b.RepetitionCount() >= 5
+}
+
+// enPassantCapturable reports whether a pawn of the side to move can take
+// en passant without leaving its own king in check.
+func (b *Board) enPassantCapturable() bool {
+	target := b.EnPassant
+	king := b.GetKing(b.Turn)
+	if target == nil || king == nil {
+		return false
+	}
+	pawnRow := target.Row + 1
+	if b.Turn == BLACK {
+		pawnRow = target.Row - 1
+	}
+	captured := b.Squares[pawnRow][target.Column]
+
+	for _, col := range []int{target.Column - 1, target.Column + 1} {
+		from, ok := b.GetSquareIfExists(pawnRow, col)
+		if !ok || from.Piece.Type() != PAWN || !from.Piece.IsAlly(b.Turn) {
+			continue
+		}
+		if b.enPassantLeavesKingSafe(from, target, captured, king) {
+			return true
+		}
+	}
+	return false
+}
+
+// enPassantLeavesKingSafe plays the capture on the squares alone, checks the
+// king and puts the pieces back.
+func (b *Board) enPassantLeavesKingSafe(from, target, captured *Square, king *King) bool {
+	pawn, capturedPawn, targetPiece := from.Piece, captured.Piece, target.Piece
+	from.Piece, captured.Piece, target.Piece = &Null{}, &Null{}, pawn
+	safe := len(b.attackers(king.Square(), ENEMY[b.Turn])) == 0
+	from.Piece, captured.Piece, target.Piece = pawn, capturedPawn, targetPiece
+	return safe
+}
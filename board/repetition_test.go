@@ -0,0 +1,132 @@
+package board
+
+import (
+	"context"
+	"testing"
+)
+
+func playSans(t *testing.T, board *Board, sans ...string) {
+	t.Helper()
+	for _, san := range sans {
+		move, err := board.ParseSan(san)
+		if err != nil {
+			t.Fatalf("ParseSan(%q) returned error: %s", san, err.Message)
+		}
+		if _, err := board.MovePiece(move); err != nil {
+			t.Fatalf("MovePiece(%s) returned error: %s", san, err.Message)
+		}
+	}
+}
+
+func TestPositionFen(t *testing.T) {
+	tests := []struct {
+		fen      string
+		expected string
+	}{
+		{
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+			"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq -",
+		},
+		{
+			"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3",
+			"rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6",
+		},
+		{
+			// the e5 pawn is pinned to the king by the rook on a5
+			"8/8/8/r2pPK2/8/8/8/4k3 w - d6 0 1",
+			"8/8/8/r2pPK2/8/8/8/4k3 w - -",
+		},
+		{
+			"4k3/8/8/8/2pP4/8/8/4K3 b - d3 0 1",
+			"4k3/8/8/8/2pP4/8/8/4K3 b - d3",
+		},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		if fen := board.PositionFen(); fen != tt.expected {
+			t.Fatalf("%s: position fen should be %s. Got %s", tt.fen, tt.expected, fen)
+		}
+	}
+}
+
+func TestRepetition(t *testing.T) {
+	board := New()
+	board.SetupPieces()
+	board.Evaluate(BLACK)
+
+	shuffle := []string{"Nf3", "Nf6", "Ng1", "Ng8"}
+	playSans(t, board, shuffle...)
+	if count := board.RepetitionCount(); count != 2 || board.Draw {
+		t.Fatalf("start position should have occurred twice without a draw. Got %d, draw %t", count, board.Draw)
+	}
+
+	playSans(t, board, shuffle...)
+	if count := board.RepetitionCount(); count != 3 || board.Draw || board.ClaimableDraw() != DRAW_REPETITION {
+		t.Fatalf("start position should be a threefold repetition to claim. Got %d, draw %t (%q)", count, board.Draw, board.ClaimableDraw())
+	}
+
+	board.UndoMove()
+	if count := board.RepetitionCount(); count != 2 || board.Draw {
+		t.Fatalf("undo should leave a position seen twice. Got %d, draw %t", count, board.Draw)
+	}
+	playSans(t, board, "Ng8")
+
+	playSans(t, board, shuffle...)
+	playSans(t, board, shuffle...)
+	if count := board.RepetitionCount(); count != 5 || !board.Draw || board.DrawReason() != DRAW_FIVEFOLD_REPETITION {
+		t.Fatalf("start position should be a fivefold repetition. Got %d, draw %t (%q)", count, board.Draw, board.DrawReason())
+	}
+}
+
+func TestRepetitionAfterLostCastlingRights(t *testing.T) {
+	board := New()
+	board.SetupFromFen("4k3/8/8/8/8/8/8/4K2R w K - 0 1")
+	board.Evaluate(BLACK)
+
+	shuffle := []string{"Kf1", "Ke7", "Ke1", "Ke8"}
+	playSans(t, board, shuffle...)
+	if count := board.RepetitionCount(); count != 1 {
+		t.Fatalf("losing castling rights should make a new position. Got %d", count)
+	}
+	playSans(t, board, shuffle...)
+	playSans(t, board, shuffle...)
+	if count := board.RepetitionCount(); count != 3 || !board.DrawByRepetition() {
+		t.Fatalf("position without castling rights should repeat three times. Got %d", count)
+	}
+}
+
+func TestRepetitionCountsCastlesAndCaptures(t *testing.T) {
+	board := New()
+	board.SetupFromFen("4k3/8/8/8/8/8/7r/R3K3 w Q - 0 1")
+	board.Evaluate(BLACK)
+
+	playSans(t, board, "O-O-O", "Rh3", "Kb1", "Rh2", "Kc1")
+	if count := board.RepetitionCount(); count != 2 {
+		t.Fatalf("position after castling should have occurred twice. Got %d", count)
+	}
+	playSans(t, board, "Rh1", "Rxh1")
+	if count := board.RepetitionCount(); count != 1 || board.HalfmoveClock != 0 {
+		t.Fatalf("capture should start a new repetition window. Got %d, clock %d", count, board.HalfmoveClock)
+	}
+}
+
+func TestSearchFromClaimableDraw(t *testing.T) {
+	board := New()
+	board.SetupPieces()
+	board.Evaluate(BLACK)
+	shuffle := []string{"Nf3", "Nf6", "Ng1", "Ng8"}
+	playSans(t, board, shuffle...)
+	playSans(t, board, shuffle...)
+
+	// the game goes on until the draw is claimed, so there is still a move
+	// to find
+	result := board.Search(context.Background(), WHITE, SearchLimits{Depth: 2})
+	if result.Move == nil {
+		t.Fatalf("expected a move from a position where a draw can be claimed")
+	}
+	if board.ClaimableDraw() != DRAW_REPETITION {
+		t.Fatalf("search should leave the threefold repetition. Got %q", board.ClaimableDraw())
+	}
+}
@@ -0,0 +1,224 @@
+package board
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrBadSan        = errors.New("malformed move notation")
+	ErrIllegalMove   = errors.New("illegal move")
+	ErrAmbiguousMove = errors.New("ambiguous move")
+)
+
+var sanPattern = regexp.MustCompile(`^([NBRQK])?([a-h])?([1-8])?(x)?([a-h][1-8])(?:=?([NBRQ]))?$`)
+
+var sanPieces = map[string]string{
+	"N": KNIGHT,
+	"B": BISHOP,
+	"R": ROOK,
+	"Q": QUEEN,
+	"K": KING,
+}
+
+// San returns move in Standard Algebraic Notation. The move must be legal in
+// the current position; it is played and taken back to find the check and
+// mate suffixes. En passant captures are written without an "e.p." suffix,
+// as PGN requires.
+func (b *Board) San(move *Move) string {
+	return b.san(move, false)
+}
+
+// SanEnPassant returns move as San does, but marks an en passant capture
+// the way it is written for people to read, as in "exd6 e.p.".
+func (b *Board) SanEnPassant(move *Move) string {
+	return b.san(move, true)
+}
+
+func (b *Board) san(move *Move, markEnPassant bool) string {
+	san := b.sanWithoutSuffix(move)
+	if markEnPassant && move.IsEnPassant() {
+		san += " e.p."
+	}
+
+	trial := *move
+	trial.Turn = move.Piece.Color()
+	if _, err := b.MovePiece(&trial); err != nil {
+		return san
+	}
+	switch {
+	case b.Checkmate:
+		san += "#"
+	case b.GetKing(ENEMY[trial.Turn]).Checked:
+		san += "+"
+	}
+	b.UndoMove()
+	return san
+}
+
+func (b *Board) sanWithoutSuffix(move *Move) string {
+	if move.IsCastle() {
+		if move.To.Column == COL_G {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+
+	to := strings.ToLower(move.To.Name)
+	capture := move.IsCapture()
+
+	if move.Piece.Type() == PAWN {
+		san := ""
+		if capture {
+			san = fileName(move.From) + "x"
+		}
+		san += to
+		if move.IsPromotion() {
+			san += "=" + pieceLetter(move.promotionType())
+		}
+		return san
+	}
+
+	san := pieceLetter(move.Piece.Type()) + b.disambiguation(move)
+	if capture {
+		san += "x"
+	}
+	return san + to
+}
+
+// disambiguation returns the file, rank or square of the moving piece when
+// another piece of the same type can also reach the destination.
+func (b *Board) disambiguation(move *Move) string {
+	sameFile, sameRank, others := false, false, false
+	for _, other := range b.GetAllValidMoves(move.Piece.Color()) {
+		if other.To != move.To || other.From == move.From || other.Piece.Type() != move.Piece.Type() {
+			continue
+		}
+		others = true
+		if other.From.Column == move.From.Column {
+			sameFile = true
+		}
+		if other.From.Row == move.From.Row {
+			sameRank = true
+		}
+	}
+	switch {
+	case !others:
+		return ""
+	case !sameFile:
+		return fileName(move.From)
+	case !sameRank:
+		return rankName(move.From)
+	default:
+		return strings.ToLower(move.From.Name)
+	}
+}
+
+// ParseSan finds the legal move for the side to move that matches san.
+// Check, mate and annotation suffixes are ignored, "0-0" is accepted for
+// castling and an "e.p." suffix is accepted on en passant captures.
+func (b *Board) ParseSan(san string) (*Move, *Error) {
+	text := cleanSan(san)
+	color := b.Turn
+
+	if text == "O-O" || text == "O-O-O" {
+		for _, move := range b.GetAllValidMoves(color) {
+			if !move.IsCastle() {
+				continue
+			}
+			if (text == "O-O") == (move.To.Column == COL_G) {
+				return move, nil
+			}
+		}
+		return nil, wrapError(ErrIllegalMove, "%s: %s cannot castle", san, color)
+	}
+
+	parts := sanPattern.FindStringSubmatch(text)
+	if parts == nil {
+		return nil, wrapError(ErrBadSan, "%q is not a valid SAN move", san)
+	}
+	pieceType := PAWN
+	if parts[1] != "" {
+		pieceType = sanPieces[parts[1]]
+	}
+	toRow, toCol, _ := parseSquareName(parts[5])
+
+	candidates := []*Move{}
+	for _, move := range b.GetAllValidMoves(color) {
+		switch {
+		case move.Piece.Type() != pieceType:
+		case move.To.Row != toRow || move.To.Column != toCol:
+		case parts[2] != "" && fileName(move.From) != parts[2]:
+		case parts[3] != "" && rankName(move.From) != parts[3]:
+		case move.IsPromotion() != (parts[6] != ""):
+		case move.IsPromotion() && move.Promotion.Type() != sanPieces[parts[6]]:
+		default:
+			candidates = append(candidates, move)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, wrapError(ErrIllegalMove, "%s is not a legal move for %s", san, color)
+	case 1:
+		return candidates[0], nil
+	default:
+		return nil, wrapError(ErrAmbiguousMove, "%s matches %d legal moves for %s", san, len(candidates), color)
+	}
+}
+
+func cleanSan(san string) string {
+	text := strings.TrimSpace(san)
+	text = strings.TrimRight(text, "+#!?")
+	text = strings.TrimSuffix(text, "e.p.")
+	text = strings.TrimSpace(text)
+	text = strings.TrimRight(text, "+#!?")
+	return strings.ReplaceAll(text, "0", "O")
+}
+
+// IsCastle reports whether the move is a king moving two squares.
+func (m *Move) IsCastle() bool {
+	return m.Piece.Type() == KING && calcOffset(m.From.Column, m.To.Column) == 2
+}
+
+// IsEnPassant reports whether the move is a pawn capturing onto an empty
+// square, which only an en passant capture can be.
+func (m *Move) IsEnPassant() bool {
+	return m.Piece.Type() == PAWN && m.From.Column != m.To.Column && m.To.Piece.Type() == NULL
+}
+
+// IsPromotion reports whether the move takes a pawn to the last rank.
+func (m *Move) IsPromotion() bool {
+	return m.Piece.Type() == PAWN && (m.To.Row == ROW_1 || m.To.Row == ROW_8)
+}
+
+// promotionType is the piece a promotion move creates, a queen unless
+// another piece was chosen.
+func (m *Move) promotionType() string {
+	if m.Promotion == nil || m.Promotion.Type() == NULL {
+		return QUEEN
+	}
+	return m.Promotion.Type()
+}
+
+// IsCapture reports whether the move takes a piece, including en passant.
+// It must be called before the move is played.
+func (m *Move) IsCapture() bool {
+	if m.To.Piece.IsEnemy(m.Piece.Color()) {
+		return true
+	}
+	return m.Piece.Type() == PAWN && m.From.Column != m.To.Column
+}
+
+func pieceLetter(pieceType string) string {
+	return strings.ToUpper(fenSymbols[pieceType])
+}
+
+func fileName(sq *Square) string {
+	return strings.ToLower(sq.Name[:1])
+}
+
+func rankName(sq *Square) string {
+	return sq.Name[1:]
+}
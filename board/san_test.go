@@ -0,0 +1,179 @@
+package board
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSan(t *testing.T) {
+	tests := []struct {
+		fen      string
+		from     string
+		to       string
+		expected string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2", "e4", "e4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "g1", "f3", "Nf3"},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/3PP3/8/PPP2PPP/RNBQKBNR w KQkq - 1 3", "d4", "e5", "dxe5"},
+		{"4k3/8/8/8/8/2p5/8/1N2K3 w - - 0 1", "b1", "c3", "Nxc3"},
+		{"8/8/8/8/4k3/2p5/8/1N2K3 w - - 0 1", "b1", "c3", "Nxc3+"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1", "g1", "O-O"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8", "c8", "O-O-O"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", "e5", "d6", "exd6"},
+		{"k7/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7", "e8", "e8=Q+"},
+		{"4k3/8/8/8/8/8/8/RN2K3 w Q - 0 1", "b1", "d2", "Nd2"},
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "b1", "d2", "Nbd2"},
+		{"4k3/8/8/8/8/8/4K3/R6R w - - 0 1", "a1", "d1", "Rad1"},
+		{"4k3/R7/8/8/8/8/4K3/R7 w - - 0 1", "a1", "a4", "R1a4"},
+		{"4k3/8/8/8/7Q/8/K7/4Q2Q w - - 0 1", "h1", "e4", "Qh1e4+"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1", "a8", "Ra8#"},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		board.Evaluate(ENEMY[board.Turn])
+		fromRow, fromCol, _ := parseSquareName(tt.from)
+		toRow, toCol, _ := parseSquareName(tt.to)
+		from := board.Squares[fromRow][fromCol]
+		move := &Move{
+			Turn:  board.Turn,
+			Piece: from.Piece,
+			From:  from,
+			To:    board.Squares[toRow][toCol],
+		}
+		san := board.San(move)
+		if san != tt.expected {
+			t.Fatalf("%s: SAN should be %s. Got %s", tt.fen, tt.expected, san)
+		}
+		if fen := board.Fen(); fen != tt.fen {
+			t.Fatalf("San should leave the board unchanged. Fen should be %s. Got %s", tt.fen, fen)
+		}
+	}
+}
+
+func TestSanEnPassant(t *testing.T) {
+	tests := []struct {
+		fen           string
+		from          string
+		to            string
+		plain, marked string
+	}{
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", "e5", "d6", "exd6", "exd6 e.p."},
+		{"8/4k3/8/3pP3/8/8/8/4K3 w - d6 0 2", "e5", "d6", "exd6+", "exd6 e.p.+"},
+		{"4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1", "d4", "e3", "dxe3", "dxe3 e.p."},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/3PP3/8/PPP2PPP/RNBQKBNR w KQkq - 1 3", "d4", "e5", "dxe5", "dxe5"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "g1", "f3", "Nf3", "Nf3"},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		board.Evaluate(ENEMY[board.Turn])
+		fromRow, fromCol, _ := parseSquareName(tt.from)
+		toRow, toCol, _ := parseSquareName(tt.to)
+		from := board.Squares[fromRow][fromCol]
+		move := &Move{
+			Turn:  board.Turn,
+			Piece: from.Piece,
+			From:  from,
+			To:    board.Squares[toRow][toCol],
+		}
+		if san := board.San(move); san != tt.plain {
+			t.Fatalf("%s: SAN should be %s. Got %s", tt.fen, tt.plain, san)
+		}
+		san := board.SanEnPassant(move)
+		if san != tt.marked {
+			t.Fatalf("%s: SAN with en passant marked should be %s. Got %s", tt.fen, tt.marked, san)
+		}
+		parsed, err := board.ParseSan(san)
+		if err != nil {
+			t.Fatalf("ParseSan(%q) returned error: %s", san, err.Message)
+		}
+		if parsed.From != move.From || parsed.To != move.To {
+			t.Fatalf("ParseSan(%q) should find %s -> %s. Got %s -> %s", san, move.From.Name, move.To.Name, parsed.From.Name, parsed.To.Name)
+		}
+	}
+}
+
+func TestParseSan(t *testing.T) {
+	tests := []struct {
+		fen          string
+		san          string
+		expectedFrom string
+		expectedTo   string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e4", "E2", "E4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nf3", "G1", "F3"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1", "Nc6", "B8", "C6"},
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/3PP3/8/PPP2PPP/RNBQKBNR w KQkq - 1 3", "dxe5", "D4", "E5"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "O-O", "E1", "G1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "0-0-0", "E8", "C8"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", "exd6 e.p.", "E5", "D6"},
+		{"k7/4P3/8/8/8/8/8/4K3 w - - 0 1", "e8=Q+", "E7", "E8"},
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "Nbd2", "B1", "D2"},
+		{"4k3/8/8/8/7Q/8/K7/4Q2Q w - - 0 1", "Qh1e4+", "H1", "E4"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "Ra8#", "A1", "A8"},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "Ra8!?", "A1", "A8"},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		board.Evaluate(ENEMY[board.Turn])
+		move, err := board.ParseSan(tt.san)
+		if err != nil {
+			t.Fatalf("%s: ParseSan(%q) returned error: %s", tt.fen, tt.san, err.Message)
+		}
+		if move.From.Name != tt.expectedFrom || move.To.Name != tt.expectedTo {
+			t.Fatalf("%s should be %s -> %s. Got %s -> %s", tt.san, tt.expectedFrom, tt.expectedTo, move.From.Name, move.To.Name)
+		}
+	}
+}
+
+func TestParseSanErrors(t *testing.T) {
+	tests := []struct {
+		fen      string
+		san      string
+		expected error
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e5", ErrIllegalMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nc6", ErrIllegalMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "O-O", ErrIllegalMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Zz9", ErrBadSan},
+		{"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1", "Nd2", ErrAmbiguousMove},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		board.Evaluate(ENEMY[board.Turn])
+		_, err := board.ParseSan(tt.san)
+		if !errors.Is(err, tt.expected) {
+			t.Fatalf("ParseSan(%q) should return %q. Got %v", tt.san, tt.expected, err)
+		}
+	}
+}
+
+func TestSanRoundTrip(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1",
+	}
+	for _, fen := range fens {
+		board := New()
+		board.SetupFromFen(fen)
+		board.Evaluate(ENEMY[board.Turn])
+		for _, move := range board.GetAllValidMoves(board.Turn) {
+			san := board.San(move)
+			parsed, err := board.ParseSan(san)
+			if err != nil {
+				t.Fatalf("%s: ParseSan(%q) returned error: %s", fen, san, err.Message)
+			}
+			if parsed.From != move.From || parsed.To != move.To {
+				t.Fatalf("%s: %s should parse to %s -> %s. Got %s -> %s", fen, san, move.From.Name, move.To.Name, parsed.From.Name, parsed.To.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package board
+
+import (
+	"context"
+	"math"
+	"slices"
+	"time"
+)
+
+// MAX_SEARCH_DEPTH is the deepest iteration Search will start.
+const MAX_SEARCH_DEPTH = 64
+
+// CHECKMATE_SCORE is the score MiniMax gives a checkmate on the board it
+// starts from, positive when white delivers it.
+const CHECKMATE_SCORE = 99.9
+
+// MATE_PLY_SCORE is taken off CHECKMATE_SCORE for every ply between the
+// start of the search and the checkmate, so the winner prefers the
+// quickest mate and the loser the slowest.
+const MATE_PLY_SCORE = 0.01
+
+// MAX_MATE_PLY is the furthest a checkmate is told apart from a nearer one.
+const MAX_MATE_PLY = 2 * MAX_SEARCH_DEPTH
+
+// MATE_BOUND is the smallest score, either way, that stands for a mate.
+const MATE_BOUND = CHECKMATE_SCORE - MAX_MATE_PLY*MATE_PLY_SCORE
+
+// now is the clock Search times itself and its deadline by. Tests replace
+// it to run the search against a clock they control.
+var now = time.Now
+
+// mateScore returns the score of the side turn being checkmated ply moves
+// into the search.
+func mateScore(turn string, ply int) float64 {
+	score := CHECKMATE_SCORE - float64(min(ply, MAX_MATE_PLY))*MATE_PLY_SCORE
+	if turn == WHITE {
+		return -score
+	}
+	return score
+}
+
+func isMateScore(score float64) bool {
+	return math.Abs(score) >= MATE_BOUND
+}
+
+// matePly returns how many plies into the search the checkmate that score
+// stands for is.
+func matePly(score float64) int {
+	return int(math.Round((CHECKMATE_SCORE - math.Abs(score)) / MATE_PLY_SCORE))
+}
+
+// MateIn returns how many moves the winning side needs to checkmate when
+// score, from the start of a search, is a mate score: positive when white
+// mates and negative when black does. It returns 0 for any other score.
+func MateIn(score float64) int {
+	if !isMateScore(score) {
+		return 0
+	}
+	moves := (matePly(score) + 1) / 2
+	if score < 0 {
+		return -moves
+	}
+	return moves
+}
+
+// SearchLimits bounds a Search. Zero fields are no limit, and a search with
+// no limits at all runs to MAX_SEARCH_DEPTH.
+type SearchLimits struct {
+	Depth    int           // deepest iteration to search
+	MoveTime time.Duration // time allowed from the start of the search
+	Nodes    uint64        // positions to visit
+	Deadline time.Time     // wall-clock time to stop by
+	// MultiPV is how many of the best moves to find, each with its own
+	// score and line. Zero finds just the best one.
+	MultiPV int
+	// Options turns off parts of the selective search.
+	Options SearchOptions
+}
+
+// SearchResult is the outcome of the last iteration Search completed. PV is
+// the principal variation: the line of play the search expects, starting
+// with Move. Use Board.SanLine to print it. Mate is MateIn(Score).
+type SearchResult struct {
+	Move  *Move
+	Score float64
+	Mate  int
+	PV    []*Move
+	// Lines holds the best moves found, best first. Its first line is
+	// Move, Score and PV.
+	Lines []Line
+	Depth int
+	Nodes uint64
+	Time  time.Duration
+}
+
+// Line is a move with its score and the line of play expected after it.
+// Mate is MateIn(Score).
+type Line struct {
+	Move  *Move
+	Score float64
+	Mate  int
+	PV    []*Move
+}
+
+// searchState follows a running search so MiniMax can stop when a limit is
+// reached or the search's context is done. A nil state never stops.
+type searchState struct {
+	ctx       context.Context
+	limits    SearchLimits
+	deadline  time.Time
+	nodes     uint64
+	stopped   bool
+	rootMoves int
+	// depth is the depth of the iteration being searched, and nullMoves
+	// the null moves made on the line being searched.
+	depth     int
+	nullMoves int
+	// pv[ply] is the best line found from the node being searched at ply.
+	pv [][]*Move
+	// excluded holds root moves already reported by a multi-PV search.
+	excluded []ttMove
+	// killers[ply] holds the last two quiet moves that caused a cutoff at
+	// ply, and history scores quiet moves by side and squares for the
+	// cutoffs they caused anywhere in the search.
+	killers [][2]ttMove
+	history [2][64][64]int
+}
+
+func newSearchState(ctx context.Context, limits SearchLimits, start time.Time, rootMoves int) *searchState {
+	s := &searchState{ctx: ctx, limits: limits, deadline: limits.Deadline, rootMoves: rootMoves}
+	if limits.MoveTime > 0 {
+		moveDeadline := start.Add(limits.MoveTime)
+		if s.deadline.IsZero() || moveDeadline.Before(s.deadline) {
+			s.deadline = moveDeadline
+		}
+	}
+	return s
+}
+
+// visit counts a node and reports whether the search must stop. The clock
+// and the context are only checked every few nodes.
+func (s *searchState) visit() bool {
+	if s == nil {
+		return false
+	}
+	if s.stopped {
+		return true
+	}
+	s.nodes++
+	if s.limits.Nodes > 0 && s.nodes >= s.limits.Nodes {
+		s.stopped = true
+	}
+	if s.nodes&63 == 0 && s.expired() {
+		s.stopped = true
+	}
+	return s.stopped
+}
+
+func (s *searchState) expired() bool {
+	if s.ctx.Err() != nil {
+		return true
+	}
+	return !s.deadline.IsZero() && !now().Before(s.deadline)
+}
+
+func (s *searchState) aborted() bool {
+	return s != nil && s.stopped
+}
+
+// ply returns how many moves deep into the search the board is.
+func (s *searchState) ply(b *Board) int {
+	if s == nil {
+		return 0
+	}
+	return len(b.Moves) - s.rootMoves + s.nullMoves
+}
+
+// clearPV empties the line of the node at ply, before it is searched.
+func (s *searchState) clearPV(ply int) {
+	if s == nil {
+		return
+	}
+	for len(s.pv) <= ply+1 {
+		s.pv = append(s.pv, nil)
+	}
+	s.pv[ply] = s.pv[ply][:0]
+}
+
+// updatePV makes move, followed by the line of the node it leads to, the
+// line of the node at ply.
+func (s *searchState) updatePV(ply int, move *Move) {
+	if s == nil {
+		return
+	}
+	s.pv[ply] = append(append(s.pv[ply][:0], move), s.pv[ply+1]...)
+}
+
+// excludedAt reports whether move is left out of the node at ply.
+func (s *searchState) excludedAt(ply int, move *Move) bool {
+	if s == nil || ply != 0 {
+		return false
+	}
+	return slices.Contains(s.excluded, packMove(move))
+}
+
+// excluding reports whether moves are left out of the node at ply, so its
+// result is not the true score of the position.
+func (s *searchState) excluding(ply int) bool {
+	return s != nil && ply == 0 && len(s.excluded) > 0
+}
+
+// Search finds a move for turn by iterative deepening: it runs MiniMax one
+// ply deeper at a time until a limit is reached, and returns the result of
+// the last iteration that finished. An iteration cut short by a limit is
+// thrown away. If not even the first one finishes, the first legal move is
+// returned. The search ends early once it has found a checkmate within its
+// depth, since no deeper iteration can find a quicker one.
+//
+// With limits.MultiPV above one, every iteration searches the root again
+// for each further line, leaving out the moves already found.
+//
+// Cancelling ctx stops the search within a few nodes, and Search returns
+// what it has found so far.
+func (b *Board) Search(ctx context.Context, turn string, limits SearchLimits) SearchResult {
+	start := now()
+	if turn != b.Turn {
+		// moves are only made for the side to move, so a search for the
+		// other side plays as if it had the move
+		defer b.restoreTurn(b.passTurn())
+	}
+	b.search = newSearchState(ctx, limits, start, len(b.Moves))
+	defer func() { b.search = nil }()
+
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > MAX_SEARCH_DEPTH {
+		maxDepth = MAX_SEARCH_DEPTH
+	}
+
+	result := SearchResult{}
+	for depth := 1; depth <= maxDepth && ctx.Err() == nil; depth++ {
+		lines := b.searchLines(turn, depth, max(limits.MultiPV, 1))
+		if b.search.aborted() {
+			break
+		}
+		result.Lines, result.Depth = lines, depth
+		if len(lines) == 0 || isMateScore(lines[0].Score) && matePly(lines[0].Score) <= depth {
+			break
+		}
+	}
+	if len(result.Lines) == 0 {
+		if moves := b.GetAllValidMoves(turn); len(moves) > 0 {
+			result.Lines = []Line{{Move: moves[0], PV: []*Move{moves[0]}}}
+		}
+	}
+	if len(result.Lines) > 0 {
+		best := result.Lines[0]
+		result.Move, result.Score, result.Mate, result.PV = best.Move, best.Score, best.Mate, best.PV
+	}
+	result.Nodes = b.search.nodes
+	result.Time = now().Sub(start)
+	return result
+}
+
+// searchLines runs one iteration of Search, finding up to count lines.
+func (b *Board) searchLines(turn string, depth, count int) []Line {
+	b.search.depth = depth
+	b.search.excluded = b.search.excluded[:0]
+	lines := []Line{}
+	for len(lines) < count {
+		move, score := b.MiniMax(turn, math.Inf(-1), math.Inf(1), depth)
+		if b.search.aborted() || move == nil || move.From == nil {
+			break
+		}
+		lines = append(lines, Line{Move: move, Score: score, Mate: MateIn(score), PV: slices.Clone(b.search.pv[0])})
+		b.search.excluded = append(b.search.excluded, packMove(move))
+	}
+	b.search.excluded = b.search.excluded[:0]
+	return lines
+}
+
+// SanLine returns moves, a line of play from the current position, in SAN.
+// The moves are replayed on the board by their UCI names and taken back
+// again.
+func (b *Board) SanLine(moves []*Move) ([]string, *Error) {
+	sans := []string{}
+	played := 0
+	defer func() {
+		for ; played > 0; played-- {
+			b.UndoMove()
+		}
+	}()
+	for _, move := range moves {
+		replay, err := b.ParseUci(move.Uci())
+		if err != nil {
+			return sans, err
+		}
+		sans = append(sans, b.San(replay))
+		if _, err := b.MovePiece(replay); err != nil {
+			return sans, err
+		}
+		played++
+	}
+	return sans, nil
+}
@@ -0,0 +1,273 @@
+package board
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func searchBoard(t *testing.T, fen string) *Board {
+	t.Helper()
+	board := New()
+	if err := board.SetupFromFen(fen); err != nil {
+		t.Fatalf("SetupFromFen returned error: %s", err.Message)
+	}
+	board.Evaluate(ENEMY[board.Turn])
+	return board
+}
+
+func checkSearchResult(t *testing.T, board *Board, fen string, result SearchResult) {
+	t.Helper()
+	if result.Move == nil {
+		t.Fatalf("search returned no move")
+	}
+	if board.Fen() != fen {
+		t.Fatalf("search left the board at %s", board.Fen())
+	}
+	if _, err := board.MovePiece(result.Move); err != nil {
+		t.Fatalf("search returned an illegal move: %s", err.Message)
+	}
+}
+
+func TestSearchDepthLimit(t *testing.T) {
+	board := searchBoard(t, kiwipeteFen)
+	result := board.Search(context.Background(), WHITE, SearchLimits{Depth: 3})
+	if result.Depth != 3 {
+		t.Fatalf("expected depth 3, got %d", result.Depth)
+	}
+	if result.Nodes == 0 {
+		t.Fatalf("expected nodes to be counted")
+	}
+	checkSearchResult(t, board, kiwipeteFen, result)
+}
+
+func TestSearchNodeLimit(t *testing.T) {
+	board := searchBoard(t, kiwipeteFen)
+	result := board.Search(context.Background(), WHITE, SearchLimits{Nodes: 5000})
+	if result.Nodes > 5000 {
+		t.Fatalf("expected at most 5000 nodes, got %d", result.Nodes)
+	}
+	if result.Depth < 1 || result.Depth >= MAX_SEARCH_DEPTH {
+		t.Fatalf("expected a shallow completed iteration, got depth %d", result.Depth)
+	}
+	checkSearchResult(t, board, kiwipeteFen, result)
+}
+
+func TestSearchMoveTime(t *testing.T) {
+	// a clock that moves on a millisecond each time it is read makes the
+	// deadline fall at the same node on every machine
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+	t.Cleanup(func() { now = time.Now })
+
+	board := searchBoard(t, kiwipeteFen)
+	result := board.Search(context.Background(), WHITE, SearchLimits{MoveTime: 100 * time.Millisecond})
+	// the clock is read when the search starts and then every 64 nodes, so
+	// the hundredth read after the start is the first at the deadline
+	if result.Nodes != 100*64 {
+		t.Fatalf("expected the search to stop at the deadline after %d nodes, got %d", 100*64, result.Nodes)
+	}
+	if result.Time != 101*time.Millisecond {
+		t.Fatalf("expected the search to take 101ms by its clock, got %s", result.Time)
+	}
+	if result.Depth < 1 {
+		t.Fatalf("expected an iteration to finish before the deadline, got depth %d", result.Depth)
+	}
+	checkSearchResult(t, board, kiwipeteFen, result)
+}
+
+func TestSearchPassedDeadline(t *testing.T) {
+	board := searchBoard(t, kiwipeteFen)
+	// the deadline is only checked every few nodes, so use a node limit
+	// too to be sure the first iteration can't finish
+	result := board.Search(context.Background(), WHITE, SearchLimits{Deadline: time.Now().Add(-time.Second), Nodes: 10})
+	if result.Depth != 0 {
+		t.Fatalf("expected no iteration to finish, got depth %d", result.Depth)
+	}
+	checkSearchResult(t, board, kiwipeteFen, result)
+}
+
+func TestSearchStopsAtMate(t *testing.T) {
+	fen := "rqb5/pkpP4/ppp5/8/8/8/8/4K3 w - - 0 1"
+	board := searchBoard(t, fen)
+	result := board.Search(context.Background(), WHITE, SearchLimits{MoveTime: 10 * time.Second})
+	if result.Move.Uci() != "d7d8n" || result.Mate != 1 {
+		t.Fatalf("expected d7d8n mating in 1, got %s scored %f", result.Move.Uci(), result.Score)
+	}
+	if result.Depth != 1 {
+		t.Fatalf("expected the search to stop after depth 1, got %d", result.Depth)
+	}
+	checkSearchResult(t, board, fen, result)
+}
+
+// pollContext counts how often the search polls it for cancellation, and
+// reports itself cancelled once it has been polled more than limit times.
+// The search polls at the same points on every run, so the cancel comes at
+// the same point too.
+type pollContext struct {
+	context.Context
+	polls, limit int
+}
+
+func (c *pollContext) Err() error {
+	c.polls++
+	if c.limit > 0 && c.polls > c.limit {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestSearchCancel(t *testing.T) {
+	board := searchBoard(t, kiwipeteFen)
+	first := &pollContext{Context: context.Background()}
+	expected := board.Search(first, WHITE, SearchLimits{Depth: 1})
+
+	// one more poll starts the second iteration, and the next cancels it
+	ctx := &pollContext{Context: context.Background(), limit: first.polls + 1}
+	result := board.Search(ctx, WHITE, SearchLimits{})
+	if result.Depth != 1 {
+		t.Fatalf("expected the search to be cancelled in its second iteration, got depth %d", result.Depth)
+	}
+	if result.Move.Uci() != expected.Move.Uci() || math.Abs(result.Score-expected.Score) > 1e-9 {
+		t.Fatalf("expected the first iteration's %s scored %f, got %s scored %f",
+			expected.Move.Uci(), expected.Score, result.Move.Uci(), result.Score)
+	}
+	if result.Nodes <= expected.Nodes {
+		t.Fatalf("expected the second iteration to have started")
+	}
+	if result.Time > 10*time.Second {
+		t.Fatalf("search ran for %s after its context was cancelled", result.Time)
+	}
+	checkSearchResult(t, board, kiwipeteFen, result)
+}
+
+func TestSearchCancelledBeforeStart(t *testing.T) {
+	board := searchBoard(t, kiwipeteFen)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := board.Search(ctx, WHITE, SearchLimits{})
+	if result.Depth != 0 || result.Nodes != 0 {
+		t.Fatalf("expected no search, got depth %d after %d nodes", result.Depth, result.Nodes)
+	}
+	checkSearchResult(t, board, kiwipeteFen, result)
+}
+
+func TestSearchPrincipalVariation(t *testing.T) {
+	board := searchBoard(t, kiwipeteFen)
+	result := board.Search(context.Background(), WHITE, SearchLimits{Depth: 3})
+	// the line runs on through the captures quiescence looked at
+	if len(result.PV) < 3 {
+		t.Fatalf("expected a line of at least three moves, got %d", len(result.PV))
+	}
+	if result.PV[0].Uci() != result.Move.Uci() {
+		t.Fatalf("line starts with %s, not the best move %s", result.PV[0].Uci(), result.Move.Uci())
+	}
+	sans, err := board.SanLine(result.PV)
+	if err != nil {
+		t.Fatalf("line is not playable: %s", err.Message)
+	}
+	if len(sans) != len(result.PV) {
+		t.Fatalf("expected %d SAN moves, got %v", len(result.PV), sans)
+	}
+	if board.Fen() != kiwipeteFen {
+		t.Fatalf("SanLine left the board at %s", board.Fen())
+	}
+}
+
+func TestSearchMatingLine(t *testing.T) {
+	fen := "2r3k1/5ppp/8/8/8/8/3R1PPP/3R2K1 w - - 0 1"
+	board := searchBoard(t, fen)
+	result := board.Search(context.Background(), WHITE, SearchLimits{Depth: 5})
+	sans, err := board.SanLine(result.PV)
+	if err != nil {
+		t.Fatalf("line is not playable: %s", err.Message)
+	}
+	expected := []string{"Rd8+", "Rxd8", "Rxd8#"}
+	if !slices.Equal(sans, expected) {
+		t.Fatalf("expected line %v, got %v", expected, sans)
+	}
+	if result.Mate != 2 || result.Score != mateScore(BLACK, 3) {
+		t.Fatalf("expected mate in 2, got %d scored %f", result.Mate, result.Score)
+	}
+	if result.Depth != 3 {
+		t.Fatalf("expected the search to stop once the mate was inside its depth, got depth %d", result.Depth)
+	}
+}
+
+func TestSearchMatedLine(t *testing.T) {
+	fen := "3r2k1/3r1ppp/8/8/8/8/5PPP/2R3K1 b - - 0 1"
+	board := searchBoard(t, fen)
+	board.TT = NewTranspositionTable(1)
+	result := board.Search(context.Background(), BLACK, SearchLimits{Depth: 5})
+	if result.Mate != -2 || result.Move.Uci() != "d7d1" {
+		t.Fatalf("expected Rd1+ mating in 2, got %s with mate %d", result.Move.Uci(), result.Mate)
+	}
+	checkSearchResult(t, board, fen, result)
+}
+
+func TestMateIn(t *testing.T) {
+	tests := []struct {
+		score    float64
+		expected int
+	}{
+		{mateScore(BLACK, 1), 1},
+		{mateScore(WHITE, 2), -1},
+		{mateScore(BLACK, 5), 3},
+		{mateScore(WHITE, 6), -3},
+		{mateScore(BLACK, MAX_MATE_PLY), MAX_MATE_PLY / 2},
+		{12.5, 0},
+		{-MATE_BOUND + 0.5, 0},
+	}
+	for _, tt := range tests {
+		if got := MateIn(tt.score); got != tt.expected {
+			t.Errorf("MateIn(%f): expected %d, got %d", tt.score, tt.expected, got)
+		}
+	}
+}
+
+func TestSearchMultiPV(t *testing.T) {
+	fen := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	board := searchBoard(t, fen)
+	result := board.Search(context.Background(), WHITE, SearchLimits{Depth: 2, MultiPV: 3})
+	if len(result.Lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(result.Lines))
+	}
+	if result.Move != result.Lines[0].Move || result.Score != result.Lines[0].Score {
+		t.Fatalf("result does not match its first line")
+	}
+	seen := map[string]bool{}
+	for i, line := range result.Lines {
+		if seen[line.Move.Uci()] {
+			t.Fatalf("%s found twice", line.Move.Uci())
+		}
+		seen[line.Move.Uci()] = true
+		if line.PV[0] != line.Move {
+			t.Fatalf("line %d does not start with its move", i)
+		}
+		if i > 0 && line.Score > result.Lines[i-1].Score+1e-9 {
+			t.Fatalf("line %d scores %f, above the line before it", i, line.Score)
+		}
+		if _, err := board.SanLine(line.PV); err != nil {
+			t.Fatalf("line %d is not playable: %s", i, err.Message)
+		}
+	}
+
+	single := searchBoard(t, fen).Search(context.Background(), WHITE, SearchLimits{Depth: 2})
+	if math.Abs(single.Score-result.Score) > 1e-9 {
+		t.Fatalf("best line scored %f, single search scored %f", result.Score, single.Score)
+	}
+}
+
+func TestSearchMultiPVFewerMoves(t *testing.T) {
+	// Kb8 is black's only move
+	board := searchBoard(t, "k7/8/1K6/8/8/8/8/7R b - - 0 1")
+	result := board.Search(context.Background(), BLACK, SearchLimits{Depth: 2, MultiPV: 5})
+	if len(result.Lines) != 1 || result.Move.Uci() != "a8b8" {
+		t.Fatalf("expected only Kb8 to be found, got %d lines", len(result.Lines))
+	}
+}
@@ -0,0 +1,103 @@
+package board
+
+// kindTypes names the piece kinds in kindIndex order.
+var kindTypes = [6]string{PAWN, KNIGHT, BISHOP, ROOK, QUEEN, KING}
+
+func kindValue(kind int) float64 {
+	return PieceValues[kindTypes[kind]]
+}
+
+// leastValuable returns the square and kind of the least valuable of the
+// pieces of color in attackers, or -1 if there is none.
+func (b *Board) leastValuable(attackers Bitboard, color int) (int, int) {
+	for kind := PAWN_INDEX; kind <= KING_INDEX; kind++ {
+		if found := attackers & b.bb.pieces[color][kind]; found != 0 {
+			return found.first(), kind
+		}
+	}
+	return -1, -1
+}
+
+// SEE is the static exchange evaluation of move: the material its side
+// wins, or loses if negative, once every capture on the destination square
+// that pays off has been made. Each side takes back with its least valuable
+// piece, pieces behind the ones that have taken join in, and either side
+// may stop taking when going on would lose more. The king only takes a
+// piece nobody defends. Pins are not looked at. A move that takes nothing
+// scores what it loses if the piece can be taken.
+func (b *Board) SEE(move *Move) float64 {
+	from, to := squareIndex(move.From), squareIndex(move.To)
+	occupied := b.bb.all &^ squareBit(from)
+
+	captured := 0.0
+	if kind := capturedKind(move); kind >= 0 {
+		captured = kindValue(kind)
+		if move.To.Piece.Type() == NULL {
+			// en passant: the pawn taken is beside the destination
+			occupied &^= squareBit(squareIndex(move.From)/8*8 + move.To.Column)
+		}
+	}
+	onSquare := kindValue(kindIndex(move.Piece.Type()))
+	if move.IsPromotion() {
+		captured += kindValue(kindIndex(move.promotionType())) - kindValue(PAWN_INDEX)
+		onSquare = kindValue(kindIndex(move.promotionType()))
+	}
+	return b.exchange(to, occupied, colorIndex(ENEMY[move.Turn]), captured, onSquare)
+}
+
+// exchange plays out the captures on to after a first capture that won
+// captured and left a piece worth onSquare there, with side to take back.
+// It returns what the side that made the first capture wins.
+func (b *Board) exchange(to int, occupied Bitboard, side int, captured, onSquare float64) float64 {
+	gain := make([]float64, 1, 32)
+	gain[0] = captured
+	for {
+		// pieces that have taken are gone from occupied, uncovering the
+		// sliders behind them
+		from, kind := b.leastValuable(b.attackersTo(to, side, occupied)&occupied, side)
+		if from < 0 {
+			break
+		}
+		if kind == KING_INDEX && b.attackersTo(to, 1-side, occupied)&occupied != 0 {
+			break
+		}
+		// what side wins by taking, if the other side stops there
+		gain = append(gain, onSquare-gain[len(gain)-1])
+		occupied &^= squareBit(from)
+		onSquare = kindValue(kind)
+		side = 1 - side
+	}
+	for d := len(gain) - 1; d > 0; d-- {
+		gain[d-1] = -max(-gain[d-1], gain[d])
+	}
+	return gain[0]
+}
+
+// HangingPieces returns the pieces of color the other side wins material
+// by taking, judged by SEE.
+func (b *Board) HangingPieces(color string) []Piece {
+	side := colorIndex(color)
+	enemy := 1 - side
+	hanging := []Piece{}
+	for bb := b.bb.occupied[side] &^ b.bb.pieces[side][KING_INDEX]; bb != 0; bb &= bb - 1 {
+		idx := bb.first()
+		from, kind := b.leastValuable(b.attackersTo(idx, enemy, b.bb.all), enemy)
+		if from < 0 {
+			continue
+		}
+		piece := b.squareAt(idx).Piece
+		capture := &Move{
+			Turn:  ENEMY[color],
+			Piece: b.squareAt(from).Piece,
+			From:  b.squareAt(from),
+			To:    b.squareAt(idx),
+		}
+		if kind == PAWN_INDEX && capture.IsPromotion() {
+			capture.Promotion = b.CreatePiece(ENEMY[color], QUEEN)
+		}
+		if b.SEE(capture) > 0 {
+			hanging = append(hanging, piece)
+		}
+	}
+	return hanging
+}
@@ -0,0 +1,67 @@
+package board
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSEE(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		move     string
+		expected float64
+	}{
+		{"pawn takes defended knight", "4k3/8/3p4/4n3/3P4/8/8/4K3 w - - 0 1", "d4e5", 2.05},
+		{"queen takes defended pawn", "4k3/8/3p4/4p3/8/8/8/4QK2 w - - 0 1", "e1e5", -8.5},
+		{"rook backed by a rook", "4r1k1/8/8/4p3/8/8/4R3/4R1K1 w - - 0 1", "e2e5", 1},
+		{"rook alone", "4r1k1/8/8/4p3/8/8/4R3/6K1 w - - 0 1", "e2e5", -4.63},
+		{"king can't take a defended piece", "8/8/3k4/4p3/8/5N2/1B6/4K3 w - - 0 1", "f3e5", 1},
+		{"king takes back", "8/8/3k4/4p3/8/5N2/8/4K3 w - - 0 1", "f3e5", -2.05},
+		{"en passant", "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 1},
+		{"quiet move to an attacked square", "4k3/8/3p4/8/8/5N2/8/4K3 w - - 0 1", "f3e5", -3.05},
+		{"quiet move to a safe square", "4k3/8/3p4/8/8/5N2/8/4K3 w - - 0 1", "f3d4", 0},
+		{"promotion taken back", "3rk3/2P5/8/8/8/8/8/4K3 w - - 0 1", "c7c8q", -1},
+	}
+	for _, tt := range tests {
+		board := searchBoard(t, tt.fen)
+		move, err := board.ParseUci(tt.move)
+		if err != nil {
+			t.Fatalf("%s: ParseUci returned error: %s", tt.name, err.Message)
+		}
+		if got := board.SEE(move); math.Abs(got-tt.expected) > 1e-9 {
+			t.Errorf("%s: expected SEE %.2f, got %.2f", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestHangingPieces(t *testing.T) {
+	board := searchBoard(t, "4k3/8/8/3q4/4P3/5P2/8/4K3 b - - 0 1")
+	hanging := board.HangingPieces(BLACK)
+	if len(hanging) != 1 || hanging[0].Type() != QUEEN {
+		t.Fatalf("expected the black queen to hang, got %d pieces", len(hanging))
+	}
+	if hanging := board.HangingPieces(WHITE); len(hanging) != 0 {
+		t.Fatalf("expected the defended pawn not to hang, got %d pieces", len(hanging))
+	}
+
+	board = searchBoard(t, "4k3/8/8/3q4/4P3/8/8/4K3 b - - 0 1")
+	hanging = board.HangingPieces(WHITE)
+	if len(hanging) != 1 || hanging[0].Square().Name != "E4" {
+		t.Fatalf("expected the undefended pawn to hang, got %d pieces", len(hanging))
+	}
+}
+
+func TestQuiescenceSkipsLosingCaptures(t *testing.T) {
+	// QxP loses the queen to the pawn on d6
+	board := searchBoard(t, "4k3/8/3p4/4p3/8/8/8/4QK2 w - - 0 1")
+	board.search = &searchState{}
+	moves := board.generateMoves(WHITE, true)
+	if len(moves) != 1 || !board.losesMaterial(moves[0]) {
+		t.Fatalf("expected Qxe5 to be the only capture, losing material")
+	}
+	board.Quiescence(WHITE, math.Inf(-1), math.Inf(1))
+	if board.search.nodes != 1 {
+		t.Fatalf("expected quiescence to stand pat without searching Qxe5, visited %d nodes", board.search.nodes)
+	}
+}
@@ -0,0 +1,168 @@
+package board
+
+// Selective search settings. MiniMax searches some moves less deeply than
+// others: it skips the moves of a position where even passing the turn
+// would be too good for the side to move, searches quiet moves late in the
+// move order less deeply, and searches checks one ply deeper.
+const (
+	// NULL_MOVE_REDUCTION is how much shallower the search after a null
+	// move is, on top of the ply the null move takes.
+	NULL_MOVE_REDUCTION = 2
+	// NULL_MOVE_WINDOW is the width of the window searched after a null
+	// move.
+	NULL_MOVE_WINDOW = 0.01
+	// NULL_MOVE_MIN_DEPTH is the least depth a null move is tried at.
+	NULL_MOVE_MIN_DEPTH = 3
+	// LMR_MIN_DEPTH is the least depth moves are reduced at.
+	LMR_MIN_DEPTH = 3
+	// LMR_MIN_MOVES is how many moves of a node are searched in full
+	// before the quiet ones are reduced.
+	LMR_MIN_MOVES = 3
+)
+
+// SearchOptions turns off parts of the selective search, so what each one
+// is worth can be measured. The zero value uses all of them.
+type SearchOptions struct {
+	NoNullMove        bool
+	NoLateReductions  bool
+	NoCheckExtensions bool
+}
+
+// inCheck reports whether the king of turn is in check.
+func (b *Board) inCheck(turn string) bool {
+	king := b.kingOf(colorIndex(turn))
+	return king != nil && king.Checked
+}
+
+// hasPieces reports whether turn has a piece other than its king and pawns.
+// Without one zugzwang is likely, and passing the turn says nothing about
+// the position.
+func (b *Board) hasPieces(turn string) bool {
+	pieces := b.bb.pieces[colorIndex(turn)]
+	return pieces[KNIGHT_INDEX]|pieces[BISHOP_INDEX]|pieces[ROOK_INDEX]|pieces[QUEEN_INDEX] != 0
+}
+
+// makeNullMove passes the turn to the other side without moving a piece.
+// It returns the state undoNullMove needs to take it back.
+func (b *Board) makeNullMove() gameState {
+	b.search.nullMoves++
+	return b.passTurn()
+}
+
+func (b *Board) undoNullMove(prev gameState) {
+	b.search.nullMoves--
+	b.restoreTurn(prev)
+}
+
+// passTurn gives the move to the other side without moving a piece. It
+// returns the state restoreTurn needs to give it back.
+func (b *Board) passTurn() gameState {
+	prev := gameState{
+		turn:           b.Turn,
+		enPassant:      b.EnPassant,
+		halfmoveClock:  b.HalfmoveClock,
+		fullmoveNumber: b.FullmoveNumber,
+		hash:           b.hash,
+	}
+	prevKey := b.stateKey()
+	b.Turn = ENEMY[prev.turn]
+	b.EnPassant = nil
+	b.hash ^= prevKey ^ b.stateKey()
+	b.Evaluate(prev.turn)
+	return prev
+}
+
+func (b *Board) restoreTurn(prev gameState) {
+	b.Turn = prev.turn
+	b.EnPassant = prev.enPassant
+	b.HalfmoveClock = prev.halfmoveClock
+	b.FullmoveNumber = prev.fullmoveNumber
+	b.hash = prev.hash
+	b.Checkmate = false
+	b.Stalemate = false
+	b.Draw = false
+	b.Evaluate(ENEMY[prev.turn])
+}
+
+// tryNullMove reports whether turn could pass and still score at least beta
+// for white, or at most alpha for black, searched depth plies deep. If so
+// the node is cut off with the score returned. Only one null move is made
+// on a line, never in check and never without pieces to avoid zugzwang.
+func (b *Board) tryNullMove(turn string, alpha, beta float64, depth, ply int) (float64, bool) {
+	s := b.search
+	if s == nil || s.limits.Options.NoNullMove || ply == 0 || s.nullMoves > 0 ||
+		depth < NULL_MOVE_MIN_DEPTH || b.inCheck(turn) || !b.hasPieces(turn) {
+		return 0, false
+	}
+	// passing only helps when the position already stands well enough
+	if turn == WHITE && b.Value < beta || turn == BLACK && b.Value > alpha {
+		return 0, false
+	}
+
+	// the null move only has to show the score stays past the window
+	if turn == WHITE {
+		alpha = beta - NULL_MOVE_WINDOW
+	} else {
+		beta = alpha + NULL_MOVE_WINDOW
+	}
+	prev := b.makeNullMove()
+	_, eval := b.MiniMax(ENEMY[turn], alpha, beta, depth-1-NULL_MOVE_REDUCTION)
+	b.undoNullMove(prev)
+	if s.aborted() {
+		return 0, false
+	}
+	// a mate found after passing is not proven for the real position
+	if turn == WHITE && eval >= beta {
+		return min(eval, MATE_BOUND), true
+	}
+	if turn == BLACK && eval <= alpha {
+		return max(eval, -MATE_BOUND), true
+	}
+	return 0, false
+}
+
+// reduction returns how many plies less deeply the searched'th move of a
+// node at depth is searched. Only quiet moves that neither escape nor give
+// check are reduced.
+func (s *searchState) reduction(depth, searched int, quiet, inCheck, givesCheck bool) int {
+	if s == nil || s.limits.Options.NoLateReductions || depth < LMR_MIN_DEPTH ||
+		searched < LMR_MIN_MOVES || !quiet || inCheck || givesCheck {
+		return 0
+	}
+	if depth >= 6 && searched >= 12 {
+		return 2
+	}
+	return 1
+}
+
+// extension returns how many plies deeper a move giving check is searched
+// at ply. Extensions stop at twice the depth of the iteration so lines of
+// endless checks still end.
+func (s *searchState) extension(ply int, givesCheck bool) int {
+	if s == nil || s.limits.Options.NoCheckExtensions || !givesCheck || ply >= 2*s.depth {
+		return 0
+	}
+	return 1
+}
+
+// searchMove plays move, the searched'th of the node, and returns the score
+// of the position it leads to. A reduced search that beats the window is
+// searched again at full depth.
+func (b *Board) searchMove(turn string, move *Move, alpha, beta float64, depth, ply, searched int, inCheck bool) float64 {
+	quiet := isQuiet(move)
+	next := ENEMY[turn]
+	b.MovePiece(move)
+	givesCheck := b.inCheck(next)
+	newDepth := depth - 1 + b.search.extension(ply, givesCheck)
+
+	if reduction := b.search.reduction(depth, searched, quiet, inCheck, givesCheck); reduction > 0 {
+		_, eval := b.MiniMax(next, alpha, beta, newDepth-reduction)
+		if b.search.aborted() || turn == WHITE && eval <= alpha || turn == BLACK && eval >= beta {
+			b.UndoMove()
+			return eval
+		}
+	}
+	_, eval := b.MiniMax(next, alpha, beta, newDepth)
+	b.UndoMove()
+	return eval
+}
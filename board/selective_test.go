@@ -0,0 +1,122 @@
+package board
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestNullMove(t *testing.T) {
+	fen := "r1bqkbnr/pppp1ppp/2n5/4p3/3PP3/5N2/PPP2PPP/RNBQKB1R b KQkq d3 0 3"
+	board := searchBoard(t, fen)
+	board.search = &searchState{}
+	hash, value := board.Hash(), board.Value
+
+	prev := board.makeNullMove()
+	if board.Turn != WHITE || board.EnPassant != nil {
+		t.Fatalf("expected white to move without en passant, got %s", board.Fen())
+	}
+	if board.Hash() != board.computeHash() {
+		t.Fatalf("hash after the null move does not match the position")
+	}
+	if board.search.ply(board) != 1 {
+		t.Fatalf("expected the null move to take a ply")
+	}
+	board.undoNullMove(prev)
+
+	if board.Fen() != fen || board.Hash() != hash || math.Abs(board.Value-value) > 1e-9 {
+		t.Fatalf("null move not taken back, board at %s", board.Fen())
+	}
+	if board.search.ply(board) != 0 {
+		t.Fatalf("expected the ply to be restored")
+	}
+}
+
+func TestNullMoveGuards(t *testing.T) {
+	tests := []struct {
+		fen   string
+		depth int
+		tried bool
+	}{
+		// white is a rook up and can pass
+		{"4k3/pppp4/8/8/8/8/PPPP4/R3K3 w - - 0 1", 4, true},
+		// too shallow
+		{"4k3/pppp4/8/8/8/8/PPPP4/R3K3 w - - 0 1", 2, false},
+		// king and pawns only, so zugzwang is likely
+		{"4k3/pppp4/8/8/8/8/PPPPPP2/4K3 w - - 0 1", 4, false},
+		// in check
+		{"4k3/pppp4/8/8/8/8/PPP5/RQ1rK3 w - - 0 1", 4, false},
+	}
+	for _, tt := range tests {
+		board := searchBoard(t, tt.fen)
+		board.search = &searchState{ctx: context.Background(), rootMoves: len(board.Moves) - 1}
+		board.search.depth = tt.depth
+		_, tried := board.tryNullMove(WHITE, -1, 1, tt.depth, 1)
+		if tried != tt.tried {
+			t.Errorf("%s at depth %d: expected null move cutoff %t, got %t", tt.fen, tt.depth, tt.tried, tried)
+		}
+		if board.Fen() != tt.fen {
+			t.Fatalf("null move left the board at %s", board.Fen())
+		}
+	}
+}
+
+func TestReduction(t *testing.T) {
+	state := &searchState{}
+	tests := []struct {
+		depth, searched          int
+		quiet, inCheck, givesChk bool
+		expected                 int
+	}{
+		{4, 5, true, false, false, 1},
+		{6, 12, true, false, false, 2},
+		{4, 1, true, false, false, 0},
+		{2, 5, true, false, false, 0},
+		{4, 5, false, false, false, 0},
+		{4, 5, true, true, false, 0},
+		{4, 5, true, false, true, 0},
+	}
+	for _, tt := range tests {
+		if got := state.reduction(tt.depth, tt.searched, tt.quiet, tt.inCheck, tt.givesChk); got != tt.expected {
+			t.Errorf("reduction(%+v): expected %d, got %d", tt, tt.expected, got)
+		}
+	}
+	state.limits.Options.NoLateReductions = true
+	if got := state.reduction(6, 12, true, false, false); got != 0 {
+		t.Errorf("expected no reduction when turned off, got %d", got)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	state := &searchState{depth: 3}
+	if got := state.extension(2, true); got != 1 {
+		t.Errorf("expected a check to be extended, got %d", got)
+	}
+	if got := state.extension(2, false); got != 0 {
+		t.Errorf("expected a quiet move not to be extended, got %d", got)
+	}
+	if got := state.extension(6, true); got != 0 {
+		t.Errorf("expected no extension past twice the depth, got %d", got)
+	}
+	state.limits.Options.NoCheckExtensions = true
+	if got := state.extension(2, true); got != 0 {
+		t.Errorf("expected no extension when turned off, got %d", got)
+	}
+}
+
+func TestSearchOptions(t *testing.T) {
+	for _, options := range []SearchOptions{
+		{},
+		{NoNullMove: true},
+		{NoLateReductions: true},
+		{NoCheckExtensions: true},
+		{NoNullMove: true, NoLateReductions: true, NoCheckExtensions: true},
+	} {
+		board := searchBoard(t, kiwipeteFen)
+		result := board.Search(context.Background(), WHITE, SearchLimits{Depth: 3, Options: options})
+		if result.Depth != 3 {
+			t.Fatalf("%+v: expected depth 3, got %d", options, result.Depth)
+		}
+		checkSearchResult(t, board, kiwipeteFen, result)
+	}
+}
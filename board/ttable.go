@@ -0,0 +1,311 @@
+package board
+
+import (
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+// DEFAULT_TT_MB is the transposition table size used by BestMove when the
+// board has none.
+const DEFAULT_TT_MB = 16
+
+// Bound says how a stored score relates to the true score of a position.
+type Bound uint8
+
+const (
+	BOUND_NONE Bound = iota
+	// BOUND_EXACT scores were searched inside the alpha-beta window.
+	BOUND_EXACT
+	// BOUND_LOWER scores caused a beta cutoff; the true score is at least
+	// as high.
+	BOUND_LOWER
+	// BOUND_UPPER scores failed low; the true score is at most as high.
+	BOUND_UPPER
+)
+
+// ttMove is a move packed into the squares it joins, enough to find it
+// again among the moves of the same position.
+type ttMove struct {
+	from, to  uint8
+	promotion uint8 // kindIndex+1 of the promotion piece, or 0
+}
+
+func packMove(move *Move) ttMove {
+	if move == nil || move.From == nil {
+		return ttMove{}
+	}
+	packed := ttMove{from: uint8(squareIndex(move.From)), to: uint8(squareIndex(move.To))}
+	if move.IsPromotion() {
+		packed.promotion = uint8(kindIndex(move.promotionType()) + 1)
+	}
+	return packed
+}
+
+func (m ttMove) isNull() bool {
+	return m.from == m.to
+}
+
+func (m ttMove) matches(move *Move) bool {
+	return packMove(move) == m
+}
+
+type ttEntry struct {
+	key   uint64
+	score float64
+	move  ttMove
+	depth int8
+	bound Bound
+}
+
+// TTStats counts transposition table traffic so its size and replacement
+// can be tuned.
+type TTStats struct {
+	Probes     uint64 // lookups made
+	Hits       uint64 // lookups that found the position
+	Cutoffs    uint64 // hits whose score ended the search of a node
+	Stores     uint64 // entries written
+	Collisions uint64 // stores that replaced a different position
+}
+
+// Misses returns the number of lookups that did not find the position.
+func (s TTStats) Misses() uint64 {
+	return s.Probes - s.Hits
+}
+
+// HitRate returns the fraction of lookups that found the position.
+func (s TTStats) HitRate() float64 {
+	if s.Probes == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Probes)
+}
+
+// TranspositionTable remembers search results by Zobrist key, so a position
+// reached again through another move order is not searched twice. It has a
+// fixed number of entries and a new result replaces the one in its slot
+// unless that slot holds a deeper search of the same position. A nil table
+// stores nothing and finds nothing.
+//
+// The table may be shared by searches running on several goroutines. Slots
+// are read and written without locks; a slot torn by two goroutines writing
+// it at once no longer matches its key and is not found.
+type TranspositionTable struct {
+	slots []ttSlot
+	mask  uint64
+
+	probes, hits, cutoffs, stores, collisions atomic.Uint64
+}
+
+// ttSlot is a ttEntry packed into words that are loaded and stored
+// atomically. check is the key XORed with the other two words.
+type ttSlot struct {
+	check uint64
+	score uint64
+	data  uint64
+}
+
+func packEntry(entry ttEntry) ttSlot {
+	data := uint64(entry.move.from) |
+		uint64(entry.move.to)<<8 |
+		uint64(entry.move.promotion)<<16 |
+		uint64(uint8(entry.depth))<<24 |
+		uint64(entry.bound)<<32
+	score := math.Float64bits(entry.score)
+	return ttSlot{check: entry.key ^ score ^ data, score: score, data: data}
+}
+
+func (slot *ttSlot) load() ttSlot {
+	return ttSlot{
+		check: atomic.LoadUint64(&slot.check),
+		score: atomic.LoadUint64(&slot.score),
+		data:  atomic.LoadUint64(&slot.data),
+	}
+}
+
+func (slot *ttSlot) save(packed ttSlot) {
+	atomic.StoreUint64(&slot.check, packed.check)
+	atomic.StoreUint64(&slot.score, packed.score)
+	atomic.StoreUint64(&slot.data, packed.data)
+}
+
+func (slot ttSlot) entry() ttEntry {
+	return ttEntry{
+		key:   slot.check ^ slot.score ^ slot.data,
+		score: math.Float64frombits(slot.score),
+		move: ttMove{
+			from:      uint8(slot.data),
+			to:        uint8(slot.data >> 8),
+			promotion: uint8(slot.data >> 16),
+		},
+		depth: int8(uint8(slot.data >> 24)),
+		bound: Bound(slot.data >> 32),
+	}
+}
+
+// NewTranspositionTable returns a table using at most megabytes of memory,
+// and at least one entry. A size below zero counts as zero.
+func NewTranspositionTable(megabytes int) *TranspositionTable {
+	size := uint64(1)
+	limit := uint64(max(megabytes, 0)) << 20 / uint64(unsafe.Sizeof(ttSlot{}))
+	for size*2 <= limit {
+		size *= 2
+	}
+	return &TranspositionTable{
+		slots: make([]ttSlot, size),
+		mask:  size - 1,
+	}
+}
+
+// Len returns the number of entries the table holds.
+func (tt *TranspositionTable) Len() int {
+	if tt == nil {
+		return 0
+	}
+	return len(tt.slots)
+}
+
+// Stats returns the counts gathered since the table was made or cleared.
+func (tt *TranspositionTable) Stats() TTStats {
+	if tt == nil {
+		return TTStats{}
+	}
+	return TTStats{
+		Probes:     tt.probes.Load(),
+		Hits:       tt.hits.Load(),
+		Cutoffs:    tt.cutoffs.Load(),
+		Stores:     tt.stores.Load(),
+		Collisions: tt.collisions.Load(),
+	}
+}
+
+// Clear empties the table and resets its statistics. It must not be called
+// while a search is using the table.
+func (tt *TranspositionTable) Clear() {
+	if tt == nil {
+		return
+	}
+	clear(tt.slots)
+	for _, counter := range []*atomic.Uint64{&tt.probes, &tt.hits, &tt.cutoffs, &tt.stores, &tt.collisions} {
+		counter.Store(0)
+	}
+}
+
+func (tt *TranspositionTable) probe(key uint64) (ttEntry, bool) {
+	if tt == nil {
+		return ttEntry{}, false
+	}
+	tt.probes.Add(1)
+	entry := tt.slots[key&tt.mask].load().entry()
+	if entry.bound == BOUND_NONE || entry.key != key {
+		return ttEntry{}, false
+	}
+	tt.hits.Add(1)
+	return entry, true
+}
+
+func (tt *TranspositionTable) store(key uint64, depth int, score float64, bound Bound, move *Move) {
+	if tt == nil {
+		return
+	}
+	slot := &tt.slots[key&tt.mask]
+	if old := slot.load().entry(); old.bound != BOUND_NONE {
+		if old.key != key {
+			tt.collisions.Add(1)
+		} else if int(old.depth) > depth {
+			return
+		}
+	}
+	tt.stores.Add(1)
+	slot.save(packEntry(ttEntry{
+		key:   key,
+		score: score,
+		move:  packMove(move),
+		depth: int8(depth),
+		bound: bound,
+	}))
+}
+
+// ttCutoff reports whether a stored entry of enough depth settles the score
+// of the node searched with window alpha, beta.
+func (tt *TranspositionTable) ttCutoff(entry ttEntry, depth int, alpha, beta float64) bool {
+	if int(entry.depth) < depth {
+		return false
+	}
+	cutoff := false
+	switch entry.bound {
+	case BOUND_EXACT:
+		cutoff = true
+	case BOUND_LOWER:
+		cutoff = entry.score >= beta
+	case BOUND_UPPER:
+		cutoff = entry.score <= alpha
+	}
+	if cutoff {
+		tt.cutoffs.Add(1)
+	}
+	return cutoff
+}
+
+// scoreBound classifies the score of a node searched with window alpha,
+// beta. Scores are from white's side, as in MiniMax.
+func scoreBound(score, alpha, beta float64) Bound {
+	switch {
+	case score <= alpha:
+		return BOUND_UPPER
+	case score >= beta:
+		return BOUND_LOWER
+	default:
+		return BOUND_EXACT
+	}
+}
+
+// scoreToTT turns a mate score counted from the start of the search into
+// one counted from the node at ply, so it still holds when the node is
+// reached at another ply.
+func scoreToTT(score float64, ply int) float64 {
+	switch {
+	case score >= MATE_BOUND:
+		return score + float64(ply)*MATE_PLY_SCORE
+	case score <= -MATE_BOUND:
+		return score - float64(ply)*MATE_PLY_SCORE
+	}
+	return score
+}
+
+// scoreFromTT undoes scoreToTT for a node at ply.
+func scoreFromTT(score float64, ply int) float64 {
+	switch {
+	case score >= MATE_BOUND:
+		return score - float64(ply)*MATE_PLY_SCORE
+	case score <= -MATE_BOUND:
+		return score + float64(ply)*MATE_PLY_SCORE
+	}
+	return score
+}
+
+// ttMoveFor returns the legal move of the side to move that packed stands
+// for, or nil if there is none, as when two positions share a slot.
+func (b *Board) ttMoveFor(packed ttMove) *Move {
+	if packed.isNull() {
+		return nil
+	}
+	piece := b.squareAt(int(packed.from)).Piece
+	set := activesOf(piece)
+	if set == nil || !piece.IsAlly(b.Turn) || !set.moves().Has(int(packed.to)) {
+		return nil
+	}
+	move := &Move{
+		Turn:  b.Turn,
+		Piece: piece,
+		From:  piece.Square(),
+		To:    b.squareAt(int(packed.to)),
+	}
+	if packed.promotion != 0 {
+		move.Promotion = b.CreatePiece(b.Turn, kindTypes[packed.promotion-1])
+	}
+	if !packed.matches(move) {
+		return nil
+	}
+	return move
+}
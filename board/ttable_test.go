@@ -0,0 +1,136 @@
+package board
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+)
+
+func TestNewTranspositionTable(t *testing.T) {
+	entrySize := int(unsafe.Sizeof(ttSlot{}))
+	for _, megabytes := range []int{-1, 0, 1, 3, 16} {
+		tt := NewTranspositionTable(megabytes)
+		size := tt.Len()
+		if megabytes <= 0 && size != 1 {
+			t.Errorf("%dMB: expected the one-entry table, got %d entries", megabytes, size)
+		}
+		if size&(size-1) != 0 {
+			t.Errorf("%dMB: size %d is not a power of two", megabytes, size)
+		}
+		if megabytes > 0 && size*entrySize > megabytes<<20 {
+			t.Errorf("%dMB: %d entries use %d bytes", megabytes, size, size*entrySize)
+		}
+		if megabytes > 0 && size*2*entrySize <= megabytes<<20 {
+			t.Errorf("%dMB: %d entries leave room for twice as many", megabytes, size)
+		}
+	}
+}
+
+func TestTranspositionTableStore(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	key := uint64(0xdeadbeef)
+
+	if _, ok := tt.probe(key); ok {
+		t.Fatalf("empty table found an entry")
+	}
+	tt.store(key, 3, 1.5, BOUND_EXACT, nil)
+	entry, ok := tt.probe(key)
+	if !ok || entry.depth != 3 || entry.score != 1.5 || entry.bound != BOUND_EXACT {
+		t.Fatalf("stored entry not found: %+v", entry)
+	}
+
+	// a shallower search of the same position keeps the deeper one
+	tt.store(key, 2, 0.5, BOUND_LOWER, nil)
+	if entry, _ := tt.probe(key); entry.depth != 3 {
+		t.Fatalf("shallower entry replaced a deeper one")
+	}
+
+	// a different position in the same slot always replaces it
+	other := key + uint64(tt.Len())
+	tt.store(other, 1, -2, BOUND_UPPER, nil)
+	if _, ok := tt.probe(key); ok {
+		t.Fatalf("replaced entry still found")
+	}
+	if entry, ok := tt.probe(other); !ok || entry.score != -2 {
+		t.Fatalf("replacing entry not found")
+	}
+
+	stats := tt.Stats()
+	expected := TTStats{Probes: 5, Hits: 3, Stores: 2, Collisions: 1}
+	if stats != expected {
+		t.Fatalf("expected stats %+v, got %+v", expected, stats)
+	}
+	if stats.Misses() != 2 {
+		t.Fatalf("expected 2 misses, got %d", stats.Misses())
+	}
+
+	tt.Clear()
+	if _, ok := tt.probe(other); ok || tt.Stats().Probes != 1 {
+		t.Fatalf("Clear left entries or statistics behind")
+	}
+}
+
+func TestTTCutoff(t *testing.T) {
+	table := NewTranspositionTable(1)
+	tests := []struct {
+		entry       ttEntry
+		depth       int
+		alpha, beta float64
+		cutoff      bool
+	}{
+		{ttEntry{depth: 2, score: 1, bound: BOUND_EXACT}, 2, -5, 5, true},
+		{ttEntry{depth: 1, score: 1, bound: BOUND_EXACT}, 2, -5, 5, false},
+		{ttEntry{depth: 3, score: 6, bound: BOUND_LOWER}, 2, -5, 5, true},
+		{ttEntry{depth: 3, score: 4, bound: BOUND_LOWER}, 2, -5, 5, false},
+		{ttEntry{depth: 3, score: -6, bound: BOUND_UPPER}, 2, -5, 5, true},
+		{ttEntry{depth: 3, score: -4, bound: BOUND_UPPER}, 2, -5, 5, false},
+	}
+	for i, tc := range tests {
+		if cutoff := table.ttCutoff(tc.entry, tc.depth, tc.alpha, tc.beta); cutoff != tc.cutoff {
+			t.Errorf("test %d: expected cutoff %t, got %t", i, tc.cutoff, cutoff)
+		}
+	}
+}
+
+func TestMiniMaxUsesTranspositionTable(t *testing.T) {
+	board := New()
+	board.SetupPieces()
+	board.TT = NewTranspositionTable(1)
+
+	first, firstEval := board.MiniMax(WHITE, math.Inf(-1), math.Inf(1), 4)
+	stats := board.TT.Stats()
+	if stats.Stores == 0 {
+		t.Fatalf("search stored nothing")
+	}
+	if stats.Hits == 0 {
+		t.Fatalf("expected transpositions in the opening to be found, got %+v", stats)
+	}
+
+	// the root was stored exactly, so searching again answers from the table
+	second, secondEval := board.MiniMax(WHITE, math.Inf(-1), math.Inf(1), 4)
+	if second.Uci() != first.Uci() || secondEval != firstEval {
+		t.Fatalf("expected %s %f again, got %s %f", first.Uci(), firstEval, second.Uci(), secondEval)
+	}
+	if board.TT.Stats().Cutoffs != stats.Cutoffs+1 {
+		t.Fatalf("expected the second search to end at the root")
+	}
+	if _, err := board.MovePiece(second); err != nil {
+		t.Fatalf("move from the table is not playable: %s", err.Message)
+	}
+}
+
+func TestMateScoreTT(t *testing.T) {
+	// mate found 5 plies into a search, at a node 2 plies in
+	score := mateScore(BLACK, 5)
+	stored := scoreToTT(score, 2)
+	if stored != mateScore(BLACK, 3) {
+		t.Fatalf("expected the stored mate to count from the node, got %f", stored)
+	}
+	// the same node reached 4 plies into another search
+	if got := scoreFromTT(stored, 4); math.Abs(got-mateScore(BLACK, 7)) > 1e-9 {
+		t.Fatalf("expected a mate 7 plies in, got %f", got)
+	}
+	if got := scoreToTT(-3.5, 6); got != -3.5 {
+		t.Fatalf("expected a plain score to be stored as it is, got %f", got)
+	}
+}
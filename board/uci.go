@@ -0,0 +1,62 @@
+package board
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrBadUci = errors.New("malformed uci move")
+
+var uciPromotions = map[byte]string{
+	'n': KNIGHT,
+	'b': BISHOP,
+	'r': ROOK,
+	'q': QUEEN,
+}
+
+// Uci returns move in UCI long algebraic notation, e.g. "e2e4" or "e7e8q".
+// Castling is written as the king's two-square move.
+func (m *Move) Uci() string {
+	uci := strings.ToLower(m.From.Name + m.To.Name)
+	if m.IsPromotion() {
+		uci += fenSymbols[m.promotionType()]
+	}
+	return uci
+}
+
+// ParseUci finds the legal move for the side to move that matches uci. A
+// pawn move to the last rank without a promotion letter promotes to a queen.
+func (b *Board) ParseUci(uci string) (*Move, *Error) {
+	text := strings.ToLower(strings.TrimSpace(uci))
+	if len(text) != 4 && len(text) != 5 {
+		return nil, wrapError(ErrBadUci, "%q is not a valid uci move", uci)
+	}
+	fromRow, fromCol, fromOk := parseSquareName(text[:2])
+	toRow, toCol, toOk := parseSquareName(text[2:4])
+	if !fromOk || !toOk {
+		return nil, wrapError(ErrBadUci, "%q is not a valid uci move", uci)
+	}
+	promotion := QUEEN
+	if len(text) == 5 {
+		var ok bool
+		if promotion, ok = uciPromotions[text[4]]; !ok {
+			return nil, wrapError(ErrBadUci, "%q has an invalid promotion piece", uci)
+		}
+	}
+
+	from := b.Squares[fromRow][fromCol]
+	to := b.Squares[toRow][toCol]
+	for _, move := range b.GetAllValidMoves(b.Turn) {
+		if move.From != from || move.To != to {
+			continue
+		}
+		if len(text) == 5 && !move.IsPromotion() {
+			break
+		}
+		if move.IsPromotion() && move.Promotion.Type() != promotion {
+			continue
+		}
+		return move, nil
+	}
+	return nil, wrapError(ErrIllegalMove, "%s is not a legal move for %s", uci, b.Turn)
+}
@@ -0,0 +1,89 @@
+package board
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUci(t *testing.T) {
+	tests := []struct {
+		fen               string
+		uci               string
+		expectedFrom      string
+		expectedTo        string
+		expectedPromotion string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e4", "E2", "E4", ""},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1", "g8f6", "G8", "F6", ""},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", "E1", "G1", ""},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", "E8", "C8", ""},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2", "e5d6", "E5", "D6", ""},
+		{"k7/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7e8q", "E7", "E8", QUEEN},
+		{"k7/4P3/8/8/8/8/8/4K3 w - - 0 1", "E7E8N", "E7", "E8", KNIGHT},
+		{"k7/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7e8", "E7", "E8", ""},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		board.Evaluate(ENEMY[board.Turn])
+		move, err := board.ParseUci(tt.uci)
+		if err != nil {
+			t.Fatalf("ParseUci(%q) returned error: %s", tt.uci, err.Message)
+		}
+		if move.From.Name != tt.expectedFrom || move.To.Name != tt.expectedTo {
+			t.Fatalf("%s should be %s -> %s. Got %s -> %s", tt.uci, tt.expectedFrom, tt.expectedTo, move.From.Name, move.To.Name)
+		}
+		if tt.expectedPromotion != "" && move.Promotion.Type() != tt.expectedPromotion {
+			t.Fatalf("%s should promote to %s. Got %s", tt.uci, tt.expectedPromotion, move.Promotion.Type())
+		}
+	}
+}
+
+func TestParseUciErrors(t *testing.T) {
+	tests := []struct {
+		fen      string
+		uci      string
+		expected error
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e5", ErrIllegalMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e7e5", ErrIllegalMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2e4q", ErrIllegalMove},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2", ErrBadUci},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e2i4", ErrBadUci},
+		{"k7/4P3/8/8/8/8/8/4K3 w - - 0 1", "e7e8k", ErrBadUci},
+	}
+
+	for _, tt := range tests {
+		board := New()
+		board.SetupFromFen(tt.fen)
+		board.Evaluate(ENEMY[board.Turn])
+		_, err := board.ParseUci(tt.uci)
+		if !errors.Is(err, tt.expected) {
+			t.Fatalf("ParseUci(%q) should return %q. Got %v", tt.uci, tt.expected, err)
+		}
+	}
+}
+
+func TestUciRoundTrip(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+	}
+	for _, fen := range fens {
+		board := New()
+		board.SetupFromFen(fen)
+		board.Evaluate(ENEMY[board.Turn])
+		for _, move := range board.GetAllValidMoves(board.Turn) {
+			uci := move.Uci()
+			parsed, err := board.ParseUci(uci)
+			if err != nil {
+				t.Fatalf("%s: ParseUci(%q) returned error: %s", fen, uci, err.Message)
+			}
+			if parsed.Uci() != uci {
+				t.Fatalf("%s: %s should round trip. Got %s", fen, uci, parsed.Uci())
+			}
+		}
+	}
+}
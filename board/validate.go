@@ -0,0 +1,177 @@
+package board
+
+import "errors"
+
+var (
+	ErrBadFieldCount     = errors.New("wrong number of fen fields")
+	ErrBadRankCount      = errors.New("wrong number of ranks")
+	ErrBadRankLength     = errors.New("rank does not describe 8 squares")
+	ErrBadPieceSymbol    = errors.New("unknown piece symbol")
+	ErrBadSideToMove     = errors.New("invalid side to move")
+	ErrBadCastlingRights = errors.New("invalid castling rights")
+	ErrBadEnPassant      = errors.New("invalid en passant square")
+	ErrBadHalfmoveClock  = errors.New("invalid halfmove clock")
+	ErrBadFullmoveNumber = errors.New("invalid fullmove number")
+	ErrMissingKing       = errors.New("missing king")
+	ErrTooManyKings      = errors.New("too many kings")
+	ErrTooManyPawns      = errors.New("too many pawns")
+	ErrTooManyPieces     = errors.New("too many pieces")
+	ErrPawnOnBackRank    = errors.New("pawn on first or eighth rank")
+	ErrOpponentInCheck   = errors.New("side not to move is in check")
+	ErrTooManyCheckers   = errors.New("king attacked by more than two pieces")
+)
+
+// ParseFen builds a new board from fen and validates it. Every problem found
+// is reported, and the board is only returned when there are none.
+func ParseFen(fen string) (*Board, []*Error) {
+	pos, errs := parseFen(fen)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	b := New()
+	b.setupFromFenPosition(pos)
+
+	if castling := b.castlingFen(); castling != pos.castling {
+		errs = append(errs, wrapError(
+			ErrBadCastlingRights,
+			"castling rights %q do not match the king and rook placement (%q)",
+			pos.castling,
+			castling,
+		))
+	}
+	errs = append(errs, b.Validate()...)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return b, nil
+}
+
+// Validate checks that the position on the board could arise in a legal
+// game. Each problem is returned as an Error wrapping one of the Err
+// sentinels so callers can test for it with errors.Is.
+func (b *Board) Validate() []*Error {
+	errs := b.validateKings()
+	kingsValid := len(errs) == 0
+	errs = append(errs, b.validatePieceCounts()...)
+	errs = append(errs, b.validatePawnRanks()...)
+	errs = append(errs, b.validateEnPassant()...)
+	if kingsValid {
+		errs = append(errs, b.validateChecks()...)
+	}
+	return errs
+}
+
+func (b *Board) validateKings() []*Error {
+	errs := []*Error{}
+	for _, color := range []string{WHITE, BLACK} {
+		kings := 0
+		for piece := range b.getAllies(color) {
+			if piece.Type() == KING {
+				kings++
+			}
+		}
+		switch {
+		case kings == 0:
+			errs = append(errs, wrapError(ErrMissingKing, "%s has no king", color))
+		case kings > 1:
+			errs = append(errs, wrapError(ErrTooManyKings, "%s has %d kings", color, kings))
+		}
+	}
+	return errs
+}
+
+func (b *Board) validatePieceCounts() []*Error {
+	errs := []*Error{}
+	for _, color := range []string{WHITE, BLACK} {
+		counts := map[string]int{}
+		allies := b.getAllies(color)
+		for piece := range allies {
+			counts[piece.Type()]++
+		}
+		if counts[PAWN] > 8 {
+			errs = append(errs, wrapError(ErrTooManyPawns, "%s has %d pawns", color, counts[PAWN]))
+		}
+		if len(allies) > 16 {
+			errs = append(errs, wrapError(ErrTooManyPieces, "%s has %d pieces", color, len(allies)))
+			continue
+		}
+
+		promoted := extraPieces(counts[QUEEN], 1) +
+			extraPieces(counts[ROOK], 2) +
+			extraPieces(counts[BISHOP], 2) +
+			extraPieces(counts[KNIGHT], 2)
+		if counts[PAWN] <= 8 && counts[PAWN]+promoted > 8 {
+			errs = append(errs, wrapError(
+				ErrTooManyPieces,
+				"%s has %d promoted pieces but only %d missing pawns",
+				color,
+				promoted,
+				8-counts[PAWN],
+			))
+		}
+	}
+	return errs
+}
+
+func extraPieces(count, start int) int {
+	if count > start {
+		return count - start
+	}
+	return 0
+}
+
+func (b *Board) validatePawnRanks() []*Error {
+	errs := []*Error{}
+	for _, row := range []int{ROW_1, ROW_8} {
+		for _, sq := range b.Squares[row] {
+			if sq.Piece.Type() == PAWN {
+				errs = append(errs, wrapError(ErrPawnOnBackRank, "%s pawn on %s", sq.Piece.Color(), sq.Name))
+			}
+		}
+	}
+	return errs
+}
+
+func (b *Board) validateEnPassant() []*Error {
+	if b.EnPassant == nil {
+		return []*Error{}
+	}
+	targetRow, pawnRow, fromRow, pawnColor := ROW_6, ROW_5, ROW_7, BLACK
+	if b.Turn == BLACK {
+		targetRow, pawnRow, fromRow, pawnColor = ROW_3, ROW_4, ROW_2, WHITE
+	}
+
+	sq := b.EnPassant
+	col := sq.Column
+	pawn := b.Squares[pawnRow][col].Piece
+	switch {
+	case sq.Row != targetRow:
+		return []*Error{wrapError(ErrBadEnPassant, "en passant square %s is on the wrong rank for %s to move", sq.Name, b.Turn)}
+	case pawn.Type() != PAWN || pawn.Color() != pawnColor:
+		return []*Error{wrapError(ErrBadEnPassant, "en passant square %s has no %s pawn in front of it", sq.Name, pawnColor)}
+	case !sq.IsEmpty() || !b.Squares[fromRow][col].IsEmpty():
+		return []*Error{wrapError(ErrBadEnPassant, "en passant square %s is not behind a pawn that just moved two squares", sq.Name)}
+	}
+	return []*Error{}
+}
+
+// validateChecks requires both kings to be present exactly once.
+func (b *Board) validateChecks() []*Error {
+	errs := []*Error{}
+
+	waiting := b.GetKing(ENEMY[b.Turn])
+	if attackers := b.attackers(waiting.Square(), b.Turn); len(attackers) > 0 {
+		errs = append(errs, wrapError(
+			ErrOpponentInCheck,
+			"%s is not to move but is in check from %d pieces",
+			waiting.Color(),
+			len(attackers),
+		))
+	}
+
+	mover := b.GetKing(b.Turn)
+	if checkers := b.attackers(mover.Square(), ENEMY[b.Turn]); len(checkers) > 2 {
+		errs = append(errs, wrapError(ErrTooManyCheckers, "%s is in check from %d pieces", b.Turn, len(checkers)))
+	}
+	return errs
+}
@@ -0,0 +1,75 @@
+package board
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFen(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []error
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", nil},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", nil},
+		{"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 3", nil},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1", []error{ErrBadRankCount}},
+		{"rnbqkbnr/ppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []error{ErrBadRankLength}},
+		{"rnbqkbnr/ppppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []error{ErrBadRankLength}},
+		{"rnbqkbnr/ppppxppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []error{ErrBadPieceSymbol, ErrBadRankLength}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1", []error{ErrBadSideToMove}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w QK - 0 1", []error{ErrBadCastlingRights}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1", []error{ErrBadEnPassant}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - x 1", []error{ErrBadHalfmoveClock}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 0", []error{ErrBadFullmoveNumber}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq -", nil},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0", []error{ErrBadFieldCount}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPP1/RNBQKBNR w x e4 0 0", []error{ErrBadCastlingRights, ErrBadEnPassant, ErrBadFullmoveNumber}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQ1BNR w kq - 0 1", []error{ErrMissingKing}},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBKKBNR w kq - 0 1", []error{ErrTooManyKings}},
+		{"4k3/8/8/8/8/8/8/K3K3 w - - 0 1", []error{ErrTooManyKings}},
+		{"4k3/8/8/8/8/8/8/P3K3 w - - 0 1", []error{ErrPawnOnBackRank}},
+		{"4k2p/8/8/8/8/8/8/P3K3 w - - 0 1", []error{ErrPawnOnBackRank, ErrPawnOnBackRank}},
+		{"4k3/pppppppp/p7/8/8/8/8/4K3 w - - 0 1", []error{ErrTooManyPawns}},
+		{"4k3/pppppppp/8/8/8/8/8/QQ2K3 w - - 0 1", nil},
+		{"4k3/8/8/8/8/8/PPPPPPPP/QQQQKQQQ w - - 0 1", []error{ErrTooManyPieces}},
+		{"4k3/8/8/8/8/8/PPPPPPP1/QQ2K3 w - - 0 1", nil},
+		{"4k3/8/8/8/8/8/PPPPPPPP/QQ2K3 w - - 0 1", []error{ErrTooManyPieces}},
+		{"4k3/8/8/8/8/8/8/R3K3 w K - 0 1", []error{ErrBadCastlingRights}},
+		{"4k3/8/8/8/8/8/8/R3K3 w Q - 0 1", nil},
+		{"4k3/8/8/8/8/8/8/4K3 w - e6 0 1", []error{ErrBadEnPassant}},
+		{"4k3/8/8/4p3/8/8/8/4K3 w - e3 0 1", []error{ErrBadEnPassant}},
+		{"4k3/4p3/8/4p3/8/8/8/4K3 w - e6 0 1", []error{ErrBadEnPassant}},
+		{"4k3/8/8/8/8/8/8/4K2r w - - 0 1", nil},
+		{"4k2R/8/8/8/8/8/8/4K3 w - - 0 1", []error{ErrOpponentInCheck}},
+		{"4k3/8/8/8/8/8/8/3K1k2 w - - 0 1", []error{ErrTooManyKings}},
+		{"8/8/8/8/8/8/8/3Kk3 b - - 0 1", []error{ErrOpponentInCheck}},
+		{"4k3/8/8/8/1b6/3n4/5p2/r3K3 w - - 0 1", []error{ErrTooManyCheckers}},
+	}
+
+	for _, tt := range tests {
+		board, errs := ParseFen(tt.input)
+		if len(errs) != len(tt.expected) {
+			t.Fatalf("ParseFen(%q) should return %d errors. Got %d: %v", tt.input, len(tt.expected), len(errs), errs)
+		}
+		for i, err := range errs {
+			if !errors.Is(err, tt.expected[i]) {
+				t.Fatalf("ParseFen(%q) error %d should be %q. Got %q", tt.input, i, tt.expected[i], err.Message)
+			}
+		}
+		if len(errs) == 0 && board == nil {
+			t.Fatalf("ParseFen(%q) should return a board", tt.input)
+		}
+		if len(errs) > 0 && board != nil {
+			t.Fatalf("ParseFen(%q) should not return a board", tt.input)
+		}
+	}
+}
+
+func TestSetupFromFenErrorKind(t *testing.T) {
+	board := New()
+	err := board.SetupFromFen("rnbqkbnr/ppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	if !errors.Is(err, ErrBadRankLength) {
+		t.Fatalf("error should be %q. Got %v", ErrBadRankLength, err)
+	}
+}
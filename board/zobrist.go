@@ -0,0 +1,137 @@
+package board
+
+// Zobrist keys identify a position by a 64-bit number: the XOR of a random
+// key for every piece on its square, the side to move, each castling right
+// held and the file of a capturable en passant square. MovePiece updates the
+// board's key by XORing out what a move takes away and XORing in what it
+// adds, and UndoMove restores the key saved with the move.
+var (
+	zobristPieces      [2][6][64]uint64
+	zobristBlackToMove uint64
+	zobristCastling    [4]uint64
+	zobristEnPassant   [8]uint64
+)
+
+// castlingRights lists the castling rights in the order of zobristCastling.
+var castlingRights = [4]struct {
+	color   string
+	rookCol int
+}{
+	{WHITE, COL_H},
+	{WHITE, COL_A},
+	{BLACK, COL_H},
+	{BLACK, COL_A},
+}
+
+func init() {
+	// A fixed seed keeps keys the same from run to run, so they can be
+	// stored or compared between processes.
+	seed := uint64(0x676f6b657368)
+	next := func() uint64 {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		return z ^ (z >> 31)
+	}
+	for color := range zobristPieces {
+		for kind := range zobristPieces[color] {
+			for idx := range zobristPieces[color][kind] {
+				zobristPieces[color][kind][idx] = next()
+			}
+		}
+	}
+	zobristBlackToMove = next()
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+}
+
+// Hash returns the Zobrist key of the current position. Positions that are
+// the same for the repetition rules have the same key.
+func (b *Board) Hash() uint64 {
+	return b.hash
+}
+
+// PlacementHash returns the part of the Zobrist key that comes from the
+// pieces alone, ignoring the side to move, castling and en passant.
+func (b *Board) PlacementHash() uint64 {
+	return b.hash ^ b.stateKey()
+}
+
+// HashPlacement returns the PlacementHash of the position whose FEN piece
+// placement field is placement.
+func HashPlacement(placement string) (uint64, *Error) {
+	b := New()
+	if err := b.SetupFromFen(placement); err != nil {
+		return 0, err
+	}
+	return b.PlacementHash(), nil
+}
+
+func pieceKey(piece Piece, sq *Square) uint64 {
+	kind := kindIndex(piece.Type())
+	if kind < 0 {
+		return 0
+	}
+	return zobristPieces[colorIndex(piece.Color())][kind][squareIndex(sq)]
+}
+
+// computeHash builds the key of the position from scratch.
+func (b *Board) computeHash() uint64 {
+	key := b.stateKey()
+	for _, row := range b.Squares {
+		for _, sq := range row {
+			key ^= pieceKey(sq.Piece, sq)
+		}
+	}
+	return key
+}
+
+// stateKey returns the keys for the side to move, the castling rights and
+// the en passant square. The en passant square only counts when the side to
+// move can legally capture on it, as for PositionFen.
+func (b *Board) stateKey() uint64 {
+	key := uint64(0)
+	if b.Turn == BLACK {
+		key ^= zobristBlackToMove
+	}
+	for i, right := range castlingRights {
+		if b.castleRightAvailable(right.color, right.rookCol) {
+			key ^= zobristCastling[i]
+		}
+	}
+	if b.EnPassant != nil && b.enPassantCapturable() {
+		key ^= zobristEnPassant[b.EnPassant.Column]
+	}
+	return key
+}
+
+// moveKey returns the piece keys changed by move, which must just have been
+// executed.
+func (b *Board) moveKey(move *Move) uint64 {
+	placed := move.Piece
+	if move.Promotion != nil {
+		placed = move.Promotion
+	}
+	key := pieceKey(move.Piece, move.From) ^ pieceKey(placed, move.To)
+
+	switch move.Type {
+	case CAPTURE, EN_PASSANT:
+		captured := b.CapturedPieces[len(b.CapturedPieces)-1]
+		key ^= pieceKey(captured, captured.Square())
+	case CASTLE:
+		rook := b.Squares[move.To.Row][COL_F].Piece
+		rookFrom, rookTo := b.Squares[move.To.Row][COL_H], b.Squares[move.To.Row][COL_F]
+		if move.To.Column == COL_C {
+			rook = b.Squares[move.To.Row][COL_D].Piece
+			rookFrom, rookTo = b.Squares[move.To.Row][COL_A], b.Squares[move.To.Row][COL_D]
+		}
+		key ^= pieceKey(rook, rookFrom) ^ pieceKey(rook, rookTo)
+	}
+	return key
+}
@@ -0,0 +1,110 @@
+package board
+
+import "testing"
+
+// checkHashes walks every move sequence depth plies deep and checks that the
+// key kept up by MovePiece matches one built from scratch, and that UndoMove
+// puts the old key back.
+func checkHashes(t *testing.T, board *Board, depth int) {
+	t.Helper()
+	if depth == 0 {
+		return
+	}
+	for _, move := range board.GetAllValidMoves(board.Turn) {
+		before := board.Hash()
+		board.MovePiece(move)
+		if board.Hash() != board.computeHash() {
+			t.Fatalf("hash after %s does not match position %s", move.Uci(), board.Fen())
+		}
+		checkHashes(t, board, depth-1)
+		board.UndoMove()
+		if board.Hash() != before {
+			t.Fatalf("hash not restored after undoing %s", move.Uci())
+		}
+	}
+}
+
+func TestHashIncremental(t *testing.T) {
+	tests := []struct {
+		fen   string
+		depth int
+	}{
+		{kiwipeteFen, 2},
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", 3},
+		{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", 2},
+		{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", 2},
+	}
+	for _, tt := range tests {
+		board := New()
+		if err := board.SetupFromFen(tt.fen); err != nil {
+			t.Fatalf("SetupFromFen returned error: %s", err.Message)
+		}
+		board.Evaluate(ENEMY[board.Turn])
+		checkHashes(t, board, tt.depth)
+	}
+}
+
+func TestHashTransposition(t *testing.T) {
+	a := New()
+	a.SetupPieces()
+	playSans(t, a, "Nf3", "Nf6", "Nc3", "Nc6")
+
+	b := New()
+	b.SetupPieces()
+	playSans(t, b, "Nc3", "Nc6", "Nf3", "Nf6")
+
+	if a.Hash() != b.Hash() {
+		t.Fatalf("move order changed the hash")
+	}
+	fromFen := New()
+	fromFen.SetupFromFen(a.Fen())
+	if fromFen.Hash() != a.Hash() {
+		t.Fatalf("hash of %s differs when set up from FEN", a.Fen())
+	}
+}
+
+func TestHashState(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		// side to move
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "4k3/8/8/8/8/8/8/4K3 b - - 0 1", false},
+		// castling rights
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "r3k2r/8/8/8/8/8/8/R3K2R w Kkq - 0 1", false},
+		// en passant capture available
+		{"4k3/8/8/8/2pP4/8/8/4K3 b - d3 0 1", "4k3/8/8/8/2pP4/8/8/4K3 b - - 0 1", false},
+		// no pawn can take en passant
+		{"4k3/8/8/8/3P4/8/8/4K3 b - d3 0 1", "4k3/8/8/8/3P4/8/8/4K3 b - - 0 1", true},
+		// the clocks are not part of the position
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", "4k3/8/8/8/8/8/8/4K3 w - - 12 40", true},
+	}
+	for _, tt := range tests {
+		a, b := New(), New()
+		a.SetupFromFen(tt.a)
+		b.SetupFromFen(tt.b)
+		if same := a.Hash() == b.Hash(); same != tt.same {
+			t.Errorf("%s and %s: expected same hash %t, got %t", tt.a, tt.b, tt.same, same)
+		}
+		if a.PlacementHash() != b.PlacementHash() {
+			t.Errorf("%s and %s: expected the same placement hash", tt.a, tt.b)
+		}
+	}
+}
+
+func TestHashPlacement(t *testing.T) {
+	board := New()
+	board.SetupPieces()
+	playSans(t, board, "e4", "c6")
+
+	key, err := HashPlacement("rnbqkbnr/pp1ppppp/2p5/8/4P3/8/PPPP1PPP/RNBQKBNR")
+	if err != nil {
+		t.Fatalf("HashPlacement returned error: %s", err.Message)
+	}
+	if key != board.PlacementHash() {
+		t.Fatalf("HashPlacement does not match the board's placement hash")
+	}
+	if _, err := HashPlacement("rnbqkbnr/pp"); err == nil {
+		t.Fatalf("expected an error for a bad placement")
+	}
+}
@@ -1,6 +1,9 @@
 package bot
 
 import (
+	"context"
+	"time"
+
 	"github.com/cyamas/gokesh/board"
 	"github.com/cyamas/gokesh/bot/opening"
 )
@@ -37,6 +40,9 @@ const (
 
 	LONDON    = "LONDON"
 	CARO_KANN = "CAROKANN"
+
+	// DEFAULT_MOVE_TIME is how long a bot thinks when its MoveTime is unset.
+	DEFAULT_MOVE_TIME = 2 * time.Second
 )
 
 var ENEMY = map[string]string{
@@ -45,25 +51,45 @@ var ENEMY = map[string]string{
 }
 
 type Bot struct {
-	Name    string
-	Color   string
-	Opening *opening.Opening
+	Name     string
+	Color    string
+	Opening  *opening.Opening
+	MoveTime time.Duration
+	// LastSearch describes how the last move was chosen. For a book move
+	// only Move and PV are set.
+	LastSearch board.SearchResult
+}
+
+// Move picks the bot's move. The search stops early when ctx is done and the
+// best move found by then is returned.
+func (b *Bot) Move(ctx context.Context, brd *board.Board) *board.Move {
+	if len(brd.Moves) <= 15 {
+		return b.handleOpening(ctx, brd)
+	}
+	return b.search(ctx, brd)
 }
 
-func (b *Bot) Move(board *board.Board) *board.Move {
-	if len(board.Moves) <= 15 {
-		return b.handleOpening(board)
+// search spends the bot's move time looking for the best move.
+func (b *Bot) search(ctx context.Context, brd *board.Board) *board.Move {
+	moveTime := b.MoveTime
+	if moveTime <= 0 {
+		moveTime = DEFAULT_MOVE_TIME
+	}
+	if brd.TT == nil {
+		brd.TT = board.NewTranspositionTable(board.DEFAULT_TT_MB)
 	}
-	return board.BestMove(b.Color)
+	b.LastSearch = brd.Search(ctx, b.Color, board.SearchLimits{MoveTime: moveTime})
+	return b.LastSearch.Move
 }
 
-func (b *Bot) handleOpening(brd *board.Board) *board.Move {
+func (b *Bot) handleOpening(ctx context.Context, brd *board.Board) *board.Move {
 	if b.Color == WHITE {
 		if b.Opening == nil {
 			b.Opening = opening.Play(LONDON, brd)
 		}
 		move := b.Opening.NextMove(brd)
 		if move != nil {
+			b.LastSearch = board.SearchResult{Move: move, PV: []*board.Move{move}}
 			return move
 		}
 	} else {
@@ -72,8 +98,9 @@ func (b *Bot) handleOpening(brd *board.Board) *board.Move {
 		}
 		move := b.Opening.NextMove(brd)
 		if move != nil {
+			b.LastSearch = board.SearchResult{Move: move, PV: []*board.Move{move}}
 			return move
 		}
 	}
-	return brd.BestMove(b.Color)
+	return b.search(ctx, brd)
 }
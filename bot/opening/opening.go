@@ -53,7 +53,26 @@ type MoveFunc func(*board.Board) *board.Move
 type Opening struct {
 	Name      string
 	Variation string
-	Moves     map[string]MoveFunc
+	Moves     map[uint64]MoveFunc
+}
+
+// books holds each opening's moves keyed by the placement hash of the
+// position they are played from.
+var books = map[string]map[uint64]MoveFunc{
+	CARO_KANN: hashBook(CaroKannMoves),
+	LONDON:    hashBook(LondonMoves),
+}
+
+func hashBook(moves map[string]MoveFunc) map[uint64]MoveFunc {
+	book := make(map[uint64]MoveFunc, len(moves))
+	for placement, move := range moves {
+		key, err := board.HashPlacement(placement)
+		if err != nil {
+			panic("opening: " + err.Message)
+		}
+		book[key] = move
+	}
+	return book
 }
 
 func Play(opening string, brd *board.Board) *Opening {
@@ -61,18 +80,12 @@ func Play(opening string, brd *board.Board) *Opening {
 		Name:      opening,
 		Variation: MAIN,
 	}
-	switch opening {
-	case CARO_KANN:
-		o.Moves = CaroKannMoves
-	case LONDON:
-		o.Moves = LondonMoves
-	}
+	o.Moves = books[opening]
 	return o
 }
 
 func (o *Opening) NextMove(brd *board.Board) *board.Move {
-	fen := brd.Fen()
-	if move, ok := o.Moves[fen]; ok {
+	if move, ok := o.Moves[brd.PlacementHash()]; ok {
 		return move(brd)
 	}
 	return nil
@@ -2,9 +2,12 @@ package game
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
+	"slices"
+	"time"
 
 	"github.com/cyamas/gokesh/board"
 	"github.com/cyamas/gokesh/bot"
@@ -114,46 +117,109 @@ var ENEMY = map[string]string{
 }
 
 type Game struct {
-	Board *board.Board
-	Bot   *bot.Bot
-	Turn  string
+	Board    *board.Board
+	Bot      *bot.Bot
+	Turn     string
+	Tags     map[string]string
+	StartFen string
+	Comment  string
+	Record   []*MoveRecord
+	Result   string
+	// DrawClaim is the rule a draw was claimed under, if one was.
+	DrawClaim string
 }
 
 func New(b *board.Board) *Game {
 	colors := []string{WHITE, BLACK}
 	botColor := colors[rand.Intn(2)]
+	g := newGame(b)
+	g.Bot = &bot.Bot{Name: "Gokesh", Color: botColor}
+	g.Tags["Event"] = "Casual game"
+	g.Tags["Site"] = "Gokesh"
+	g.Tags["Date"] = time.Now().Format("2006.01.02")
+	g.Tags["Round"] = "-"
+	g.Tags["White"] = "Player"
+	g.Tags["Black"] = "Player"
+	g.Tags[botColorTag[botColor]] = g.Bot.Name
+	return g
+}
+
+var botColorTag = map[string]string{
+	WHITE: "White",
+	BLACK: "Black",
+}
+
+func newGame(b *board.Board) *Game {
 	return &Game{
-		Board: b,
-		Bot:   &bot.Bot{Name: "Gokesh", Color: botColor},
-		Turn:  WHITE,
+		Board:    b,
+		Turn:     b.Turn,
+		Tags:     map[string]string{},
+		StartFen: b.Fen(),
+		Record:   []*MoveRecord{},
+		Result:   UNFINISHED,
 	}
 }
 
 func (g *Game) ExecuteTurn(move *board.Move) (string, *Error) {
-	receipt, err := g.Board.MovePiece(move)
+	if g.Result != UNFINISHED {
+		overErr := NewError("GAME OVER: %s", g.Result)
+		return overErr.Message, overErr
+	}
+	receipt, err := g.Board.CheckMove(move)
+	if err != nil && errors.Is(err, board.ErrWrongTurn) {
+		turnErr := wrapError(err, "%s ERROR: %s", move.Piece.Color(), receipt)
+		return turnErr.Message, turnErr
+	}
 	if err != nil {
 		return g.handleBoardError(receipt, move)
 	}
+	// the SAN is read from the position before the move
+	san := g.Board.San(move)
+	if receipt, err = g.Board.MovePiece(move); err != nil {
+		return g.handleBoardError(receipt, move)
+	}
+	g.Record = append(g.Record, &MoveRecord{San: san})
 
-	receipt = fmt.Sprintf("%s %s\n", g.Turn, receipt)
+	receipt = fmt.Sprintf("%s %s", g.Turn, receipt)
 	if g.Board.Checkmate {
+		g.Result = WHITE_WINS
+		if g.Turn == BLACK {
+			g.Result = BLACK_WINS
+		}
 		return fmt.Sprintf("%s\nCHECKMATE: %s has won", receipt, g.Turn), nil
 	}
 	if g.Board.Stalemate {
+		g.Result = DRAWN
 		return fmt.Sprintf("%s\nSTALEMATE: GAME IS A DRAW", receipt), nil
 	}
 	if g.Board.Draw {
-		return fmt.Sprintf("%s\nDRAW: By repetition", receipt), nil
+		g.Result = DRAWN
+		return fmt.Sprintf("%s\nDRAW: %s", receipt, g.Board.DrawReason()), nil
 	}
 	if g.Board.GetKing(g.Turn).Checked {
 		receipt += fmt.Sprintf("\n%s IN CHECK", ENEMY[g.Turn])
 	}
-
-	receipt += fmt.Sprintf("\nBOARD VALUE: %f", g.Board.Value)
 	g.nextTurn()
 	return receipt, nil
 }
 
+// ClaimDraw draws the game for the side to move when the board lets it
+// claim one, under the fifty-move rule or threefold repetition.
+func (g *Game) ClaimDraw() (string, *Error) {
+	if g.Result != UNFINISHED {
+		overErr := NewError("GAME OVER: %s", g.Result)
+		return overErr.Message, overErr
+	}
+	reason := g.Board.ClaimableDraw()
+	if reason == "" {
+		claimErr := NewError("%s ERROR: there is no draw to claim", g.Turn)
+		return claimErr.Message, claimErr
+	}
+	g.Result = DRAWN
+	g.DrawClaim = reason
+	return fmt.Sprintf("%s CLAIMS A DRAW: %s", g.Turn, reason), nil
+}
+
 func (g *Game) handleBoardError(receipt string, move *board.Move) (string, *Error) {
 	if g.Board.GetKing(g.Turn).Checked {
 		receipt += " (KING IN CHECK)"
@@ -182,36 +248,16 @@ func (g *Game) handlePawnPromotion(move *board.Move, out io.Writer, scanner *buf
 			continue
 		}
 		promoteMsg := scanner.Text()
-		switch promoteMsg {
-		case QUEEN:
-			queen := &board.Queen{}
-			queen.SetColor(g.Turn)
-			move.Promotion = queen
-			return
-		case ROOK:
-			rook := &board.Rook{}
-			rook.SetColor(g.Turn)
-			move.Promotion = rook
-			return
-		case BISHOP:
-			bishop := &board.Bishop{}
-			bishop.SetColor(g.Turn)
-			move.Promotion = bishop
-			return
-		case KNIGHT:
-			knight := &board.Knight{}
-			knight.SetColor(g.Turn)
-			move.Promotion = knight
+		if slices.Contains(board.PromotionTypes, promoteMsg) {
+			move.Promotion = g.Board.CreatePiece(g.Turn, promoteMsg)
 			return
-		default:
-			continue
 		}
 	}
-
 }
 
 type Error struct {
 	Message string
+	Err     error
 }
 
 func NewError(format string, a ...interface{}) *Error {
@@ -219,3 +265,13 @@ func NewError(format string, a ...interface{}) *Error {
 		Message: fmt.Sprintf(format, a...),
 	}
 }
+
+// wrapError builds an Error whose cause can be matched with errors.Is.
+func wrapError(err error, format string, a ...interface{}) *Error {
+	gameErr := NewError(format, a...)
+	gameErr.Err = err
+	return gameErr
+}
+
+func (e *Error) Error() string { return e.Message }
+func (e *Error) Unwrap() error { return e.Err }
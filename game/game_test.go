@@ -60,6 +60,14 @@ func TestTurn(t *testing.T) {
 			},
 			"BOARD ERROR WHITE: ROOK: A1 -> A4 is not a valid move",
 		},
+		{
+			&board.Move{
+				Piece: b.Squares[ROW_3][COL_D].Piece,
+				From:  b.Squares[ROW_3][COL_D],
+				To:    b.Squares[ROW_4][COL_D],
+			},
+			"BOARD ERROR WHITE: NULL: D3 -> D4 is not a valid move",
+		},
 	}
 
 	for _, tt := range tests {
@@ -69,3 +77,46 @@ func TestTurn(t *testing.T) {
 		}
 	}
 }
+
+func TestClaimDraw(t *testing.T) {
+	b := board.New()
+	b.SetupFromFen("4k3/8/8/8/8/8/4P3/R3K3 w - - 98 80")
+	b.Evaluate(BLACK)
+	game := newGame(b)
+
+	if receipt, err := game.ClaimDraw(); err == nil {
+		t.Fatalf("a draw should not be claimable yet. Got '%s'", receipt)
+	}
+	move, parseErr := b.ParseSan("Ra2")
+	if parseErr != nil {
+		t.Fatalf("ParseSan returned error: %s", parseErr.Message)
+	}
+	game.ExecuteTurn(move)
+	if receipt, err := game.ClaimDraw(); err == nil {
+		t.Fatalf("the fifty moves are not up yet. Got '%s'", receipt)
+	}
+
+	move, parseErr = b.ParseSan("Kd7")
+	if parseErr != nil {
+		t.Fatalf("ParseSan returned error: %s", parseErr.Message)
+	}
+	receipt, _ := game.ExecuteTurn(move)
+	if game.Result != UNFINISHED || b.Draw {
+		t.Fatalf("the fifty-move rule should not end the game by itself. Got '%s'", receipt)
+	}
+	receipt, err := game.ClaimDraw()
+	if err != nil {
+		t.Fatalf("ClaimDraw returned error: %s", err.Message)
+	}
+	if receipt != "WHITE CLAIMS A DRAW: fifty-move rule" {
+		t.Fatalf("receipt should be 'WHITE CLAIMS A DRAW: fifty-move rule'. Got '%s'", receipt)
+	}
+	if game.Result != DRAWN || game.DrawClaim != board.DRAW_FIFTY_MOVES {
+		t.Fatalf("game should be drawn by the fifty-move rule. Got %s (%q)", game.Result, game.DrawClaim)
+	}
+
+	move, _ = b.ParseSan("Ra3")
+	if receipt, err := game.ExecuteTurn(move); err == nil {
+		t.Fatalf("no move should be played after the draw. Got '%s'", receipt)
+	}
+}
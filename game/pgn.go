@@ -0,0 +1,594 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cyamas/gokesh/board"
+)
+
+const (
+	START_FEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+	WHITE_WINS = "1-0"
+	BLACK_WINS = "0-1"
+	DRAWN      = "1/2-1/2"
+	UNFINISHED = "*"
+)
+
+var ErrBadPgn = errors.New("malformed pgn")
+
+// sevenTagRoster lists the tags every exported game carries, in the order
+// the PGN standard requires, along with their values when unknown.
+var sevenTagRoster = []struct {
+	name       string
+	unknownVal string
+}{
+	{"Event", "?"},
+	{"Site", "?"},
+	{"Date", "????.??.??"},
+	{"Round", "?"},
+	{"White", "?"},
+	{"Black", "?"},
+	{"Result", UNFINISHED},
+}
+
+var suffixNags = map[string]int{
+	"!":  1,
+	"?":  2,
+	"!!": 3,
+	"??": 4,
+	"!?": 5,
+	"?!": 6,
+}
+
+// MoveRecord is a move of a game's main line or of a variation, along with
+// the annotations attached to it in PGN.
+type MoveRecord struct {
+	San        string
+	Comment    string
+	Nags       []int
+	Variations []*Variation
+}
+
+// Variation is a line played instead of the move it is attached to, from the
+// position before that move. Comment is any comment before its first move.
+type Variation struct {
+	Comment string
+	Record  []*MoveRecord
+}
+
+// Pgn exports the game with the Seven Tag Roster, any extra tags, and the
+// main line in SAN with its comments, NAGs and variations.
+func (g *Game) Pgn() string {
+	var sb strings.Builder
+	for _, tag := range sevenTagRoster {
+		value, ok := g.Tags[tag.name]
+		if !ok || value == "" {
+			value = tag.unknownVal
+		}
+		if tag.name == "Result" {
+			value = g.Result
+		}
+		writeTag(&sb, tag.name, value)
+	}
+	if g.StartFen != START_FEN {
+		writeTag(&sb, "SetUp", "1")
+		writeTag(&sb, "FEN", g.StartFen)
+	}
+	for _, name := range g.extraTagNames() {
+		writeTag(&sb, name, g.Tags[name])
+	}
+	sb.WriteString("\n")
+	sb.WriteString(wrapMovetext(g.movetextTokens()))
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+func (g *Game) extraTagNames() []string {
+	names := []string{}
+	for name := range g.Tags {
+		switch name {
+		case "Event", "Site", "Date", "Round", "White", "Black", "Result", "SetUp", "FEN":
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *Game) movetextTokens() []string {
+	tokens := []string{}
+	if g.Comment != "" {
+		tokens = append(tokens, "{"+g.Comment+"}")
+	}
+
+	turn, moveNumber := startTurn(g.StartFen)
+	tokens = append(tokens, lineTokens(g.Record, turn, moveNumber)...)
+	return append(tokens, g.Result)
+}
+
+// lineTokens writes out the moves of a line, the first of them played by turn
+// at moveNumber, each followed by its annotations and variations.
+func lineTokens(records []*MoveRecord, turn string, moveNumber int) []string {
+	tokens := []string{}
+	needsNumber := true
+	for _, record := range records {
+		switch {
+		case turn == WHITE:
+			tokens = append(tokens, fmt.Sprintf("%d.", moveNumber))
+		case needsNumber:
+			tokens = append(tokens, fmt.Sprintf("%d...", moveNumber))
+		}
+		tokens = append(tokens, record.San)
+		for _, nag := range record.Nags {
+			tokens = append(tokens, fmt.Sprintf("$%d", nag))
+		}
+		needsNumber = false
+		if record.Comment != "" {
+			tokens = append(tokens, "{"+record.Comment+"}")
+			needsNumber = true
+		}
+		for _, variation := range record.Variations {
+			inner := lineTokens(variation.Record, turn, moveNumber)
+			if variation.Comment != "" {
+				inner = append([]string{"{" + variation.Comment + "}"}, inner...)
+			}
+			tokens = append(tokens, "("+strings.Join(inner, " ")+")")
+			needsNumber = true
+		}
+
+		if turn == BLACK {
+			moveNumber++
+		}
+		turn = ENEMY[turn]
+	}
+	return tokens
+}
+
+func startTurn(fen string) (string, int) {
+	fields := strings.Fields(fen)
+	turn, moveNumber := WHITE, 1
+	if len(fields) > 1 && fields[1] == "b" {
+		turn = BLACK
+	}
+	if len(fields) > 5 {
+		if n, err := strconv.Atoi(fields[5]); err == nil {
+			moveNumber = n
+		}
+	}
+	return turn, moveNumber
+}
+
+// wrapMovetext joins tokens into lines of at most 80 characters. Comments and
+// variations may be split across lines at spaces.
+func wrapMovetext(tokens []string) string {
+	words := []string{}
+	for _, token := range tokens {
+		words = append(words, strings.Fields(token)...)
+	}
+
+	var sb strings.Builder
+	lineLen := 0
+	for _, word := range words {
+		if lineLen > 0 && lineLen+1+len(word) > 80 {
+			sb.WriteString("\n")
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			sb.WriteString(" ")
+			lineLen++
+		}
+		sb.WriteString(word)
+		lineLen += len(word)
+	}
+	return sb.String()
+}
+
+// ReadPgn reads every game in r. Each game is replayed through the board,
+// variations included, and the first illegal, ambiguous or unreadable move is
+// reported with its game and move number.
+func ReadPgn(r io.Reader) ([]*Game, *Error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, wrapError(err, "PGN ERROR: %s", err.Error())
+	}
+	return ParsePgn(string(data))
+}
+
+// ParsePgn parses every game in text. See ReadPgn.
+func ParsePgn(text string) ([]*Game, *Error) {
+	tokens, err := lexPgn(text)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []*Game{}
+	for len(tokens) > 0 {
+		var game *Game
+		game, tokens, err = parsePgnGame(tokens, len(games)+1)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+func parsePgnGame(tokens []pgnToken, gameNumber int) (*Game, []pgnToken, *Error) {
+	tags := map[string]string{}
+	for len(tokens) > 0 && tokens[0].kind == tagToken {
+		tags[tokens[0].name] = tokens[0].text
+		tokens = tokens[1:]
+	}
+
+	startFen := START_FEN
+	if fen, ok := tags["FEN"]; ok {
+		startFen = fen
+	}
+	brd, errs := board.ParseFen(startFen)
+	if len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Message
+		}
+		return nil, nil, wrapError(errs[0], "PGN ERROR: game %d: %s", gameNumber, strings.Join(msgs, "; "))
+	}
+	brd.Evaluate(ENEMY[brd.Turn])
+
+	game := newGame(brd)
+	game.StartFen = brd.Fen()
+	game.Tags = tags
+
+	tokens, err := game.parsePgnLine(tokens, gameNumber, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if game.Result == "" {
+		game.Result = UNFINISHED
+	}
+	return game, tokens, nil
+}
+
+// parsePgnLine plays the moves in tokens on g, attaching comments, NAGs and
+// variations to them. On the main line a tag pair or a result ends the game
+// and the tokens after it, less any comments on the game, are returned. A
+// variation has neither.
+func (g *Game) parsePgnLine(tokens []pgnToken, gameNumber int, mainLine bool) ([]pgnToken, *Error) {
+	var last *MoveRecord
+	// before is the position last was played from, kept when a variation
+	// follows it
+	var before *board.Board
+	for len(tokens) > 0 {
+		token := tokens[0]
+		tokens = tokens[1:]
+
+		switch token.kind {
+		case tagToken:
+			if !mainLine {
+				return nil, wrapError(ErrBadPgn, "PGN ERROR: game %d: tag pair inside a variation", gameNumber)
+			}
+			return append([]pgnToken{token}, tokens...), nil
+		case resultToken:
+			if !mainLine {
+				return nil, wrapError(ErrBadPgn, "PGN ERROR: game %d: result inside a variation", gameNumber)
+			}
+			g.Result = token.text
+			// a comment after the result still remarks on this game
+			for len(tokens) > 0 && tokens[0].kind == commentToken {
+				if last == nil {
+					g.Comment = joinComment(g.Comment, tokens[0].text)
+				} else {
+					last.Comment = joinComment(last.Comment, tokens[0].text)
+				}
+				tokens = tokens[1:]
+			}
+			return tokens, nil
+		case moveNumberToken:
+		case commentToken:
+			if last == nil {
+				g.Comment = joinComment(g.Comment, token.text)
+			} else {
+				last.Comment = joinComment(last.Comment, token.text)
+			}
+		case nagToken:
+			if last == nil {
+				return nil, wrapError(ErrBadPgn, "PGN ERROR: game %d: NAG $%s before the first move", gameNumber, token.text)
+			}
+			nag, _ := strconv.Atoi(token.text)
+			last.Nags = append(last.Nags, nag)
+		case variationToken:
+			if last == nil {
+				return nil, wrapError(ErrBadPgn, "PGN ERROR: game %d: variation before the first move", gameNumber)
+			}
+			variation, err := parseVariation(before, token.line, gameNumber)
+			if err != nil {
+				return nil, err
+			}
+			last.Variations = append(last.Variations, variation)
+		case sanToken:
+			before = nil
+			if variationFollows(tokens) {
+				before = g.Board.Copy()
+			}
+			record, err := g.playPgnMove(token.text)
+			if err != nil {
+				return nil, wrapError(
+					err,
+					"PGN ERROR: game %d, move %s: %s",
+					gameNumber,
+					g.moveLabel(token.text),
+					err.Error(),
+				)
+			}
+			last = record
+		}
+	}
+	return tokens, nil
+}
+
+// variationFollows reports whether a variation is attached to the move whose
+// annotations start tokens.
+func variationFollows(tokens []pgnToken) bool {
+	for _, token := range tokens {
+		switch token.kind {
+		case variationToken:
+			return true
+		case sanToken, resultToken, tagToken:
+			return false
+		}
+	}
+	return false
+}
+
+// parseVariation replays the variation in tokens on its own copy of before,
+// the position before the move it is played instead of.
+func parseVariation(before *board.Board, tokens []pgnToken, gameNumber int) (*Variation, *Error) {
+	brd := before.Copy()
+	brd.Evaluate(ENEMY[brd.Turn])
+	line := newGame(brd)
+	if _, err := line.parsePgnLine(tokens, gameNumber, false); err != nil {
+		return nil, err
+	}
+	return &Variation{Comment: line.Comment, Record: line.Record}, nil
+}
+
+func joinComment(comment, text string) string {
+	if comment == "" {
+		return text
+	}
+	return comment + " " + text
+}
+
+// moveLabel formats the move about to be played as it appears in movetext,
+// e.g. "14. Nxe5" or "14... Nxe5".
+func (g *Game) moveLabel(san string) string {
+	if g.Turn == WHITE {
+		return fmt.Sprintf("%d. %s", g.Board.FullmoveNumber, san)
+	}
+	return fmt.Sprintf("%d... %s", g.Board.FullmoveNumber, san)
+}
+
+func (g *Game) playPgnMove(text string) (*MoveRecord, error) {
+	san, nag := splitSuffixNag(text)
+	move, err := g.Board.ParseSan(san)
+	if err != nil {
+		return nil, err
+	}
+	if _, execErr := g.ExecuteTurn(move); execErr != nil {
+		return nil, execErr
+	}
+	record := g.Record[len(g.Record)-1]
+	if nag > 0 {
+		record.Nags = append(record.Nags, nag)
+	}
+	return record, nil
+}
+
+// splitSuffixNag separates move suffix annotations such as "!?" from the SAN
+// and returns the NAG they stand for.
+func splitSuffixNag(text string) (string, int) {
+	san := strings.TrimRight(text, "!?")
+	return san, suffixNags[text[len(san):]]
+}
+
+type pgnTokenKind int
+
+const (
+	tagToken pgnTokenKind = iota
+	commentToken
+	variationToken
+	nagToken
+	moveNumberToken
+	resultToken
+	sanToken
+)
+
+type pgnToken struct {
+	kind pgnTokenKind
+	name string
+	text string
+	// line holds the tokens of a variation
+	line []pgnToken
+}
+
+func lexPgn(text string) ([]pgnToken, *Error) {
+	tokens := []pgnToken{}
+	runes := []rune(text)
+	lineStart := true
+
+	for i := 0; i < len(runes); {
+		ch := runes[i]
+		switch {
+		case ch == '\n':
+			lineStart = true
+			i++
+			continue
+		case unicode.IsSpace(ch):
+			i++
+			continue
+		case ch == '%' && lineStart:
+			i = skipLine(runes, i)
+			continue
+		case ch == ';':
+			end := skipLine(runes, i)
+			tokens = append(tokens, pgnToken{kind: commentToken, text: strings.TrimSpace(string(runes[i+1 : end]))})
+			i = end
+			continue
+		}
+		lineStart = false
+
+		switch {
+		case ch == '[':
+			token, end, err := lexTag(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token)
+			i = end
+		case ch == '{':
+			end := indexRune(runes, i, '}')
+			if end < 0 {
+				return nil, wrapError(ErrBadPgn, "PGN ERROR: unterminated comment")
+			}
+			tokens = append(tokens, pgnToken{kind: commentToken, text: strings.Join(strings.Fields(string(runes[i+1:end])), " ")})
+			i = end + 1
+		case ch == '(':
+			end, err := matchVariation(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			line, err := lexPgn(string(runes[i+1 : end]))
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, pgnToken{kind: variationToken, line: line})
+			i = end + 1
+		case ch == ')':
+			return nil, wrapError(ErrBadPgn, "PGN ERROR: unmatched ')'")
+		case ch == '$':
+			end := i + 1
+			for end < len(runes) && unicode.IsDigit(runes[end]) {
+				end++
+			}
+			if end == i+1 {
+				return nil, wrapError(ErrBadPgn, "PGN ERROR: '$' without a NAG number")
+			}
+			tokens = append(tokens, pgnToken{kind: nagToken, text: string(runes[i+1 : end])})
+			i = end
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("[]{}();$", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, symbolTokens(string(runes[i:end]))...)
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// symbolTokens classifies a movetext symbol. Move numbers may be written
+// against the move that follows them, as in "1.e4" or "12...Nf6". A
+// detached "e.p." after an en passant capture is dropped.
+func symbolTokens(symbol string) []pgnToken {
+	switch symbol {
+	case WHITE_WINS, BLACK_WINS, DRAWN, UNFINISHED:
+		return []pgnToken{{kind: resultToken, text: symbol}}
+	case "e.p.":
+		return []pgnToken{}
+	}
+
+	digits := strings.TrimLeftFunc(symbol, unicode.IsDigit)
+	if len(digits) < len(symbol) && strings.HasPrefix(digits, ".") {
+		san := strings.TrimLeft(digits, ".")
+		tokens := []pgnToken{{kind: moveNumberToken, text: symbol[:len(symbol)-len(san)]}}
+		if san != "" {
+			tokens = append(tokens, pgnToken{kind: sanToken, text: san})
+		}
+		return tokens
+	}
+	return []pgnToken{{kind: sanToken, text: symbol}}
+}
+
+func lexTag(runes []rune, start int) (pgnToken, int, *Error) {
+	i := start + 1
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	nameStart := i
+	for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+		i++
+	}
+	name := string(runes[nameStart:i])
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	if name == "" || i >= len(runes) || runes[i] != '"' {
+		return pgnToken{}, 0, wrapError(ErrBadPgn, "PGN ERROR: malformed tag pair")
+	}
+
+	var value strings.Builder
+	for i++; i < len(runes) && runes[i] != '"'; i++ {
+		if runes[i] == '\\' && i+1 < len(runes) {
+			i++
+		}
+		value.WriteRune(runes[i])
+	}
+	end := indexRune(runes, i, ']')
+	if i >= len(runes) || end < 0 {
+		return pgnToken{}, 0, wrapError(ErrBadPgn, "PGN ERROR: unterminated tag pair %s", name)
+	}
+	return pgnToken{kind: tagToken, name: name, text: value.String()}, end + 1, nil
+}
+
+func matchVariation(runes []rune, start int) (int, *Error) {
+	depth := 0
+	for i := start; i < len(runes); i++ {
+		switch runes[i] {
+		case '{':
+			end := indexRune(runes, i, '}')
+			if end < 0 {
+				return 0, wrapError(ErrBadPgn, "PGN ERROR: unterminated comment")
+			}
+			i = end
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, wrapError(ErrBadPgn, "PGN ERROR: unterminated variation")
+}
+
+func indexRune(runes []rune, start int, target rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+func skipLine(runes []rune, start int) int {
+	end := indexRune(runes, start, '\n')
+	if end < 0 {
+		return len(runes)
+	}
+	return end
+}
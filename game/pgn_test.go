@@ -0,0 +1,301 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cyamas/gokesh/board"
+)
+
+func TestPgnExport(t *testing.T) {
+	b := board.New()
+	b.SetupPieces()
+	game := newGame(b)
+	game.Tags["Event"] = "Test \"quoted\" event"
+	game.Tags["White"] = "Alice"
+	game.Tags["Black"] = "Bob"
+	game.Tags["Annotator"] = "Gokesh"
+
+	for _, san := range []string{"e4", "e5", "Bc4", "Nc6", "Qh5", "Nf6", "Qxf7#"} {
+		move, err := game.Board.ParseSan(san)
+		if err != nil {
+			t.Fatalf("ParseSan(%q) returned error: %s", san, err.Message)
+		}
+		game.ExecuteTurn(move)
+	}
+	game.Record[4].Comment = "threatening mate"
+	game.Record[5].Nags = []int{4}
+
+	expected := `[Event "Test \"quoted\" event"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Alice"]
+[Black "Bob"]
+[Result "1-0"]
+[Annotator "Gokesh"]
+
+1. e4 e5 2. Bc4 Nc6 3. Qh5 {threatening mate} 3... Nf6 $4 4. Qxf7# 1-0
+`
+	pgn := game.Pgn()
+	if pgn != expected {
+		t.Fatalf("pgn should be:\n%s\nGot:\n%s", expected, pgn)
+	}
+}
+
+func TestPgnExportFromFen(t *testing.T) {
+	b := board.New()
+	b.SetupFromFen("6k1/5ppp/8/8/8/8/8/R5K1 b - - 4 30")
+	b.Evaluate(WHITE)
+	game := newGame(b)
+
+	for _, san := range []string{"h6", "Ra8+"} {
+		move, err := game.Board.ParseSan(san)
+		if err != nil {
+			t.Fatalf("ParseSan(%q) returned error: %s", san, err.Message)
+		}
+		game.ExecuteTurn(move)
+	}
+
+	pgn := game.Pgn()
+	for _, expected := range []string{
+		"[SetUp \"1\"]\n",
+		"[FEN \"6k1/5ppp/8/8/8/8/8/R5K1 b - - 4 30\"]\n",
+		"30... h6 31. Ra8+ *\n",
+	} {
+		if !strings.Contains(pgn, expected) {
+			t.Fatalf("pgn should contain %q. Got:\n%s", expected, pgn)
+		}
+	}
+}
+
+func TestParsePgn(t *testing.T) {
+	input := `[Event "First"]
+[Site "?"]
+[Date "2024.01.01"]
+[Round "1"]
+[White "A"]
+[Black "B"]
+[Result "1/2-1/2"]
+
+{Opening comment} 1.e4 e5 2. Nf3 $1 Nc6 3. Bb5 a6!? {The Morphy defence}
+(3... Nf6 4. O-O (4. d3) Nxe4) 4. Ba4 ; rest of line comment
+Nf6 5. O-O 1/2-1/2
+
+% escaped line ignored
+[Event "Second"]
+[SetUp "1"]
+[FEN "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 2"]
+[Result "*"]
+
+2. exd6 e.p. Kd7 3. Kd2 *
+`
+	games, err := ParsePgn(input)
+	if err != nil {
+		t.Fatalf("ParsePgn returned error: %s", err.Message)
+	}
+	if len(games) != 2 {
+		t.Fatalf("should parse 2 games. Got %d", len(games))
+	}
+
+	first := games[0]
+	if first.Tags["Event"] != "First" || first.Result != DRAWN {
+		t.Fatalf("first game tags not parsed. Got %v, result %s", first.Tags, first.Result)
+	}
+	if len(first.Record) != 9 {
+		t.Fatalf("first game should have 9 moves. Got %d", len(first.Record))
+	}
+	if first.Comment != "Opening comment" {
+		t.Fatalf("game comment should be 'Opening comment'. Got %q", first.Comment)
+	}
+	if first.Record[2].Nags[0] != 1 {
+		t.Fatalf("Nf3 should have NAG $1. Got %v", first.Record[2].Nags)
+	}
+	sixth := first.Record[5]
+	if sixth.San != "a6" || sixth.Nags[0] != 5 || sixth.Comment != "The Morphy defence" {
+		t.Fatalf("a6 should carry $5 and a comment. Got %+v", sixth)
+	}
+	if len(sixth.Variations) != 1 {
+		t.Fatalf("a6 should carry one variation. Got %d", len(sixth.Variations))
+	}
+	variation := sixth.Variations[0].Record
+	if sans := recordSans(variation); strings.Join(sans, " ") != "Nf6 O-O Nxe4" {
+		t.Fatalf("variation should be replayed as Nf6 O-O Nxe4. Got %v", sans)
+	}
+	if sans := recordSans(variation[1].Variations[0].Record); strings.Join(sans, " ") != "d3" {
+		t.Fatalf("nested variation should be replayed as d3. Got %v", sans)
+	}
+	if first.Record[6].Comment != "rest of line comment" {
+		t.Fatalf("Ba4 should carry the line comment. Got %q", first.Record[6].Comment)
+	}
+	expectedFen := "r1bqkb1r/1ppp1ppp/p1n2n2/4p3/B3P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 3 5"
+	if fen := first.Board.Fen(); fen != expectedFen {
+		t.Fatalf("first game fen should be %s. Got %s", expectedFen, fen)
+	}
+
+	second := games[1]
+	if second.Result != UNFINISHED || len(second.Record) != 3 {
+		t.Fatalf("second game should have 3 moves and no result. Got %d moves, result %s", len(second.Record), second.Result)
+	}
+	if second.Record[0].San != "exd6" {
+		t.Fatalf("en passant should be recorded as exd6. Got %s", second.Record[0].San)
+	}
+	expectedFen = "8/3k4/3P4/8/8/8/3K4/8 b - - 2 3"
+	if fen := second.Board.Fen(); fen != expectedFen {
+		t.Fatalf("second game fen should be %s. Got %s", expectedFen, fen)
+	}
+}
+
+func TestParsePgnCommentAfterResult(t *testing.T) {
+	games, err := ParsePgn("1. e4 e5 1-0 {White resigned on time}\n\n1. d4 d5 * {Adjourned}\n\n* {No moves}")
+	if err != nil {
+		t.Fatalf("ParsePgn returned error: %s", err.Message)
+	}
+	if len(games) != 3 {
+		t.Fatalf("comments after a result should not start a game. Got %d games", len(games))
+	}
+	if comment := games[0].Record[1].Comment; comment != "White resigned on time" {
+		t.Fatalf("e5 should carry the comment after the result. Got %q", comment)
+	}
+	if comment := games[1].Record[1].Comment; comment != "Adjourned" {
+		t.Fatalf("d5 should carry the comment after the result. Got %q", comment)
+	}
+	if games[2].Comment != "No moves" {
+		t.Fatalf("a game without moves should carry the comment after its result. Got %q", games[2].Comment)
+	}
+}
+
+func TestPgnRoundTrip(t *testing.T) {
+	input := `[Event "Round trip"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "0-1"]
+
+1. f3 e5 2. g4 {blunder} (2. e4) 2... Qh4# 0-1
+`
+	games, err := ParsePgn(input)
+	if err != nil {
+		t.Fatalf("ParsePgn returned error: %s", err.Message)
+	}
+	if pgn := games[0].Pgn(); pgn != input {
+		t.Fatalf("pgn should round trip:\n%s\nGot:\n%s", input, pgn)
+	}
+}
+
+func recordSans(records []*MoveRecord) []string {
+	sans := []string{}
+	for _, record := range records {
+		sans = append(sans, record.San)
+	}
+	return sans
+}
+
+func TestPgnRoundTripVariations(t *testing.T) {
+	input := `[Event "Round trip"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+
+1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 ({Berlin} 3... Nf6 4. O-O (4. d3 Bc5) 4... Nxe4)
+(3... f5 $6) 4. Ba4 *
+`
+	games, err := ParsePgn(input)
+	if err != nil {
+		t.Fatalf("ParsePgn returned error: %s", err.Message)
+	}
+	variations := games[0].Record[5].Variations
+	if len(variations) != 2 || variations[0].Comment != "Berlin" {
+		t.Fatalf("a6 should carry two variations, the first commented. Got %+v", variations)
+	}
+	if pgn := games[0].Pgn(); pgn != input {
+		t.Fatalf("pgn should round trip:\n%s\nGot:\n%s", input, pgn)
+	}
+	expectedFen := "r1bqkbnr/1ppp1ppp/p1n5/4p3/B3P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 1 4"
+	if fen := games[0].Board.Fen(); fen != expectedFen {
+		t.Fatalf("variations should leave the main line at %s. Got %s", expectedFen, fen)
+	}
+}
+
+func TestParsePgnErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedErr     error
+		expectedMessage string
+	}{
+		{
+			"1. e4 e5 2. Nf3 Nc6 3. Bb5 Bb4 4. Bxf7 *",
+			board.ErrIllegalMove,
+			"game 1, move 4. Bxf7",
+		},
+		{
+			"1. e4 e5 *\n\n1. d4 d5 2. c4 Kd6 *",
+			board.ErrIllegalMove,
+			"game 2, move 2... Kd6",
+		},
+		{
+			"[FEN \"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1\"]\n\n1. Nd2 *",
+			board.ErrAmbiguousMove,
+			"game 1, move 1. Nd2",
+		},
+		{
+			"1. e4 e5 2. Nf3 (2. Bc4 Nf6 3. d3 Bc5 4. Qh8) Nc6 *",
+			board.ErrIllegalMove,
+			"game 1, move 4. Qh8",
+		},
+		{
+			"1. e4 e5 *\n\n1. d4 d5 (1... e5 2. dxe5 (2. d5 Kd6)) *",
+			board.ErrIllegalMove,
+			"game 2, move 2... Kd6",
+		},
+		{
+			"[FEN \"4k3/8/8/8/8/8/8/1N2KN2 w - - 0 1\"]\n\n1. Ke2 Kd7 (1... Ke7 2. Nd2) *",
+			board.ErrAmbiguousMove,
+			"game 1, move 2. Nd2",
+		},
+		{
+			"1. e4 e5 *\n\n[FEN \"8/8/8/8/8/8/8/8 w - - 0 1\"]\n\n*",
+			board.ErrMissingKing,
+			"game 2: WHITE has no king",
+		},
+		{
+			"[FEN \"4k2R/8/8/8/8/8/8/4K3 w - - 0 1\"]\n\n1. Rh7 *",
+			board.ErrOpponentInCheck,
+			"game 1: BLACK is not to move but is in check",
+		},
+		{
+			"1. e4 (1. d4 1-0) *",
+			ErrBadPgn,
+			"result inside a variation",
+		},
+		{
+			"1. e4 {unterminated",
+			ErrBadPgn,
+			"unterminated comment",
+		},
+		{
+			"1. e4 (1. d4 e5",
+			ErrBadPgn,
+			"unterminated variation",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := ParsePgn(tt.input)
+		if err == nil {
+			t.Fatalf("ParsePgn(%q) should return an error", tt.input)
+		}
+		if !errors.Is(err, tt.expectedErr) {
+			t.Fatalf("error should be %q. Got %q", tt.expectedErr, err.Message)
+		}
+		if !strings.Contains(err.Message, tt.expectedMessage) {
+			t.Fatalf("error should mention %q. Got %q", tt.expectedMessage, err.Message)
+		}
+	}
+}
@@ -1,5 +1,17 @@
 package main
 
+import (
+	"fmt"
+	"os"
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "perft" {
+		if err := runPerft(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	RunServer()
 }
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cyamas/gokesh/board"
+)
+
+// runPerft handles `gokesh perft [-fen FEN] [-divide] DEPTH`, printing the
+// number of positions DEPTH plies below the given position.
+func runPerft(args []string) error {
+	flags := flag.NewFlagSet("perft", flag.ContinueOnError)
+	fen := flags.String("fen", "", "position to count from (default: the start position)")
+	divide := flags.Bool("divide", false, "print the count below each legal move")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gokesh perft [-fen FEN] [-divide] DEPTH")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("perft: expected a depth")
+	}
+	depth, err := strconv.Atoi(flags.Arg(0))
+	if err != nil || depth < 1 {
+		return fmt.Errorf("perft: invalid depth %q", flags.Arg(0))
+	}
+
+	b := board.New()
+	if *fen == "" {
+		b.SetupPieces()
+	} else {
+		var errs []*board.Error
+		if b, errs = board.ParseFen(*fen); len(errs) > 0 {
+			msgs := make([]string, len(errs))
+			for i, err := range errs {
+				msgs[i] = err.Message
+			}
+			return fmt.Errorf("perft: invalid position:\n%s", strings.Join(msgs, "\n"))
+		}
+	}
+
+	start := time.Now()
+	nodes := uint64(0)
+	if *divide {
+		counts := b.Divide(depth)
+		moves := make([]string, 0, len(counts))
+		for move := range counts {
+			moves = append(moves, move)
+		}
+		sort.Strings(moves)
+		for _, move := range moves {
+			fmt.Printf("%s: %d\n", move, counts[move])
+			nodes += counts[move]
+		}
+		fmt.Println()
+	} else {
+		nodes = b.Perft(depth)
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("Nodes searched: %d\n", nodes)
+	fmt.Printf("Time: %s (%.0f nodes/s)\n", elapsed.Round(time.Millisecond), float64(nodes)/elapsed.Seconds())
+	return nil
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPerftRejectsInvalidPositions(t *testing.T) {
+	err := runPerft([]string{"-fen", "8/8/8/8/8/8/8/8 w - - 0 1", "2"})
+	if err == nil {
+		t.Fatalf("expected an error for a board without kings")
+	}
+	for _, msg := range []string{"WHITE has no king", "BLACK has no king"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected %q in %q", msg, err.Error())
+		}
+	}
+}
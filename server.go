@@ -5,6 +5,9 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/cyamas/gokesh/board"
 	"github.com/cyamas/gokesh/game"
@@ -15,6 +18,11 @@ import (
 const (
 	BLACK = "BLACK"
 	WHITE = "WHITE"
+
+	DEFAULT_ANALYSIS_LINES = 3
+	MAX_ANALYSIS_LINES     = 10
+	DEFAULT_ANALYSIS_TIME  = time.Second
+	MAX_ANALYSIS_TIME      = 30 * time.Second
 )
 
 var Game *game.Game
@@ -27,6 +35,9 @@ func RunServer() {
 	router.Get("/play", play)
 	router.Get("/botmove", botMove)
 	router.Post("/usermove", userMove)
+	router.Post("/claimdraw", claimDraw)
+	router.Get("/pgn", pgn)
+	router.Post("/analyse", analyse)
 	router.Handle("/static/*", http.StripPrefix("/static/", fileServer))
 	http.ListenAndServe(":3435", router)
 }
@@ -55,7 +66,10 @@ func play(w http.ResponseWriter, r *http.Request) {
 			"to":    "none",
 		}
 	} else {
-		move := Game.Bot.Move(Game.Board)
+		move := Game.Bot.Move(r.Context(), Game.Board)
+		if clientGone(r) {
+			return
+		}
 		Game.ExecuteTurn(move)
 		data = map[string]interface{}{
 			"color": "black",
@@ -63,16 +77,14 @@ func play(w http.ResponseWriter, r *http.Request) {
 			"to":    []int{move.To.Row, move.To.Column},
 		}
 	}
-	json, err := json.Marshal(data)
-	if err != nil {
-		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(json)
+	writeJSON(w, data)
 }
 
+// ClientMove is a move sent by the browser. Move holds the move in UCI
+// notation (e.g. "e7e8n"); the From/To coordinate pairs and Promotion
+// (QUEEN, ROOK, BISHOP or KNIGHT) are still accepted when it is empty.
 type ClientMove struct {
+	Move      string `json:"move"`
 	From      []int  `json:"from"`
 	To        []int  `json:"to"`
 	Promotion string `json:"promotion"`
@@ -86,24 +98,18 @@ func userMove(w http.ResponseWriter, r *http.Request) {
 		log.Println("Error decoding client request data: ", err)
 		return
 	}
-	fromRow := move.From[0]
-	fromCol := move.From[1]
-	toRow := move.To[0]
-	toCol := move.To[1]
 
-	fromSq := Game.Board.Squares[fromRow][fromCol]
-	toSq := Game.Board.Squares[toRow][toCol]
-	userMove := &board.Move{
-		Turn:  Game.Turn,
-		Piece: fromSq.Piece,
-		From:  fromSq,
-		To:    toSq,
-	}
-	if move.Promotion == "QUEEN" {
-		promotedPiece := Game.Board.CreatePiece(Game.Turn, move.Promotion)
-		userMove.Promotion = promotedPiece
-	}
 	var data map[string]interface{}
+	userMove, boardErr := move.toBoardMove(Game.Board, Game.Turn)
+	if boardErr != nil {
+		data = map[string]interface{}{
+			"valid":   false,
+			"receipt": boardErr.Message,
+		}
+		writeJSON(w, data)
+		return
+	}
+
 	receipt, gameError := Game.ExecuteTurn(userMove)
 	if gameError != nil {
 		data = map[string]interface{}{
@@ -115,15 +121,50 @@ func userMove(w http.ResponseWriter, r *http.Request) {
 			"color":     userMove.Turn,
 			"type":      userMove.Type,
 			"valid":     true,
-			"from":      move.From,
-			"to":        move.To,
+			"from":      []int{userMove.From.Row, userMove.From.Column},
+			"to":        []int{userMove.To.Row, userMove.To.Column},
+			"uci":       userMove.Uci(),
 			"eval":      Game.Board.Value,
-			"promotion": move.Promotion,
+			"promotion": "",
 			"receipt":   receipt,
 			"fen":       Game.Board.Fen(),
+			"claimable": Game.Board.ClaimableDraw(),
+		}
+		if userMove.Promotion != nil {
+			data["promotion"] = userMove.Promotion.Type()
 		}
 	}
+	writeJSON(w, data)
+}
 
+func (cm ClientMove) toBoardMove(brd *board.Board, turn string) (*board.Move, *board.Error) {
+	if cm.Move != "" {
+		return brd.ParseUci(cm.Move)
+	}
+	if len(cm.From) != 2 || len(cm.To) != 2 {
+		return nil, board.NewError("move needs from and to coordinates")
+	}
+	fromSq, fromOk := brd.GetSquareIfExists(cm.From[0], cm.From[1])
+	toSq, toOk := brd.GetSquareIfExists(cm.To[0], cm.To[1])
+	if !fromOk || !toOk {
+		return nil, board.NewError("move coordinates are off the board")
+	}
+	userMove := &board.Move{
+		Turn:  turn,
+		Piece: fromSq.Piece,
+		From:  fromSq,
+		To:    toSq,
+	}
+	if cm.Promotion != "" {
+		if !slices.Contains(board.PromotionTypes, cm.Promotion) {
+			return nil, board.NewError("%s is not a valid promotion piece", cm.Promotion)
+		}
+		userMove.Promotion = brd.CreatePiece(turn, cm.Promotion)
+	}
+	return userMove, nil
+}
+
+func writeJSON(w http.ResponseWriter, data map[string]interface{}) {
 	json, err := json.Marshal(data)
 	if err != nil {
 		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
@@ -133,6 +174,16 @@ func userMove(w http.ResponseWriter, r *http.Request) {
 	w.Write(json)
 }
 
+// pgn returns the current game as a PGN document.
+func pgn(w http.ResponseWriter, r *http.Request) {
+	if Game == nil {
+		http.Error(w, "No game in progress", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Write([]byte(Game.Pgn()))
+}
+
 func botMove(w http.ResponseWriter, r *http.Request) {
 	if Game.Board.Checkmate {
 		handleCheckmate(w)
@@ -142,17 +193,22 @@ func botMove(w http.ResponseWriter, r *http.Request) {
 		handleStalemate(w)
 		return
 	}
-	if Game.Board.Draw {
+	if Game.Board.Draw || Game.DrawClaim != "" {
 		handleDraw(w)
 		return
 	}
-	move := Game.Bot.Move(Game.Board)
+	move := Game.Bot.Move(r.Context(), Game.Board)
+	if clientGone(r) {
+		return
+	}
+	pv, _ := Game.Board.SanLine(Game.Bot.LastSearch.PV)
 	receipt, _ := Game.ExecuteTurn(move)
 	data := map[string]interface{}{
 		"type":      move.Type,
 		"color":     move.Turn,
 		"from":      []int{move.From.Row, move.From.Column},
 		"to":        []int{move.To.Row, move.To.Column},
+		"uci":       move.Uci(),
 		"eval":      Game.Board.Value,
 		"receipt":   receipt,
 		"fen":       Game.Board.Fen(),
@@ -160,6 +216,9 @@ func botMove(w http.ResponseWriter, r *http.Request) {
 		"stalemate": false,
 		"draw":      false,
 		"draw-type": "",
+		"claimable": Game.Board.ClaimableDraw(),
+		"pv":        pv,
+		"mate":      Game.Bot.LastSearch.Mate,
 	}
 	if Game.Board.Checkmate {
 		data["checkmate"] = true
@@ -169,24 +228,24 @@ func botMove(w http.ResponseWriter, r *http.Request) {
 	}
 	if Game.Board.Draw {
 		data["draw"] = true
-		switch {
-		case Game.Board.DrawByRepetition():
-			data["draw-type"] = "by repetition"
-		case Game.Board.DrawByInsufficientMaterial():
-			data["draw-type"] = "insufficient material"
-		}
+		data["draw-type"] = Game.Board.DrawReason()
 	}
 
 	if move.Promotion != nil {
 		data["promotion"] = move.Promotion.Type()
 	}
-	json, err := json.Marshal(data)
-	if err != nil {
-		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
-		return
+	writeJSON(w, data)
+}
+
+// clientGone reports whether the client went away during a search. A bot
+// move is then not played, so the game still waits for it when the client
+// asks again.
+func clientGone(r *http.Request) bool {
+	if err := r.Context().Err(); err != nil {
+		log.Printf("%s %s: search abandoned: %v", r.Method, r.URL.Path, err)
+		return true
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(json)
+	return false
 }
 
 func handleCheckmate(w http.ResponseWriter) {
@@ -194,44 +253,119 @@ func handleCheckmate(w http.ResponseWriter) {
 		"type":  "CHECKMATE",
 		"color": game.ENEMY[Game.Turn],
 	}
-	json, err := json.Marshal(data)
-	if err != nil {
-		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(json)
+	writeJSON(w, data)
 }
 
 func handleStalemate(w http.ResponseWriter) {
 	data := map[string]interface{}{
 		"type": "STALEMATE",
 	}
-	json, err := json.Marshal(data)
-	if err != nil {
-		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(json)
+	writeJSON(w, data)
 }
 
 func handleDraw(w http.ResponseWriter) {
+	reason := Game.Board.DrawReason()
+	if reason == "" {
+		reason = Game.DrawClaim
+	}
 	data := map[string]interface{}{
 		"type": "DRAW",
-		"msg":  "",
+		"msg":  reason,
 	}
-	if Game.Board.DrawByRepetition() {
-		data["msg"] = "by repetition"
+	writeJSON(w, data)
+}
+
+// claimDraw draws the game for the user when the fifty-move rule or
+// threefold repetition lets them claim it.
+func claimDraw(w http.ResponseWriter, r *http.Request) {
+	receipt, gameError := Game.ClaimDraw()
+	if gameError != nil {
+		data := map[string]interface{}{
+			"valid":   false,
+			"receipt": receipt,
+		}
+		writeJSON(w, data)
+		return
 	}
-	if Game.Board.DrawByInsufficientMaterial() {
-		data["msg"] = "insufficient material"
+	handleDraw(w)
+}
+
+// AnalysisRequest asks for the best lines in the position Fen. Lines,
+// MoveTime (in milliseconds) and Depth are optional.
+type AnalysisRequest struct {
+	Fen      string `json:"fen"`
+	Lines    int    `json:"lines"`
+	MoveTime int    `json:"movetime"`
+	Depth    int    `json:"depth"`
+}
+
+func (ar AnalysisRequest) limits() board.SearchLimits {
+	lines := ar.Lines
+	if lines <= 0 {
+		lines = DEFAULT_ANALYSIS_LINES
 	}
-	json, err := json.Marshal(data)
-	if err != nil {
-		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
+	moveTime := time.Duration(ar.MoveTime) * time.Millisecond
+	if moveTime <= 0 {
+		moveTime = DEFAULT_ANALYSIS_TIME
+	}
+	return board.SearchLimits{
+		Depth:    ar.Depth,
+		MoveTime: min(moveTime, MAX_ANALYSIS_TIME),
+		MultiPV:  min(lines, MAX_ANALYSIS_LINES),
+	}
+}
+
+// analyse searches a posted position, apart from the game in progress, and
+// returns its best moves ranked with their scores and lines in SAN, and the
+// squares of each side's hanging pieces. A position that could not arise in
+// a game is refused with every problem found, one per line.
+func analyse(w http.ResponseWriter, r *http.Request) {
+	var req AnalysisRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		log.Println("Error decoding analysis request: ", err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(json)
+	brd, errs := board.ParseFen(req.Fen)
+	if len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Message
+		}
+		http.Error(w, strings.Join(msgs, "\n"), http.StatusBadRequest)
+		return
+	}
+	brd.Evaluate(board.ENEMY[brd.Turn])
+	brd.TT = board.NewTranspositionTable(board.DEFAULT_TT_MB)
+
+	result := brd.Search(r.Context(), brd.Turn, req.limits())
+	if clientGone(r) {
+		return
+	}
+	lines := []map[string]interface{}{}
+	for _, line := range result.Lines {
+		pv, _ := brd.SanLine(line.PV)
+		lines = append(lines, map[string]interface{}{
+			"move":  line.Move.Uci(),
+			"san":   brd.San(line.Move),
+			"score": line.Score,
+			"mate":  line.Mate,
+			"pv":    pv,
+		})
+	}
+	hanging := map[string][]string{}
+	for _, color := range []string{WHITE, BLACK} {
+		hanging[color] = []string{}
+		for _, piece := range brd.HangingPieces(color) {
+			hanging[color] = append(hanging[color], strings.ToLower(piece.Square().Name))
+		}
+	}
+	data := map[string]interface{}{
+		"fen":     brd.Fen(),
+		"depth":   result.Depth,
+		"nodes":   result.Nodes,
+		"lines":   lines,
+		"hanging": hanging,
+	}
+	writeJSON(w, data)
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cyamas/gokesh/board"
+	"github.com/cyamas/gokesh/game"
+)
+
+func postAnalysis(t *testing.T, req AnalysisRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("could not encode request: %s", err)
+	}
+	rec := httptest.NewRecorder()
+	analyse(rec, httptest.NewRequest(http.MethodPost, "/analyse", strings.NewReader(string(body))))
+	return rec
+}
+
+func TestAnalyseRejectsInvalidPositions(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		expected []string
+	}{
+		{"empty board", "8/8/8/8/8/8/8/8 w - - 0 1", []string{"WHITE has no king", "BLACK has no king"}},
+		{"no white king", "4k3/8/8/8/8/8/8/8 w - - 0 1", []string{"WHITE has no king"}},
+		{"side not to move in check", "4k2R/8/8/8/8/8/8/4K3 w - - 0 1", []string{"BLACK is not to move but is in check"}},
+		{"two white kings", "4k3/8/8/8/8/8/8/3KK3 w - - 0 1", []string{"WHITE has 2 kings"}},
+		{"pawn on the back rank", "4k2P/8/8/8/8/8/8/4K3 w - - 0 1", []string{"WHITE pawn on H8"}},
+		{"malformed", "4k3/8/8 w - - 0 1", nil},
+	}
+	for _, tt := range tests {
+		rec := postAnalysis(t, AnalysisRequest{Fen: tt.fen, Depth: 1})
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", tt.name, http.StatusBadRequest, rec.Code)
+			continue
+		}
+		body := rec.Body.String()
+		if strings.TrimSpace(body) == "" {
+			t.Errorf("%s: expected the problems in the body", tt.name)
+		}
+		for _, msg := range tt.expected {
+			if !strings.Contains(body, msg) {
+				t.Errorf("%s: expected %q in %q", tt.name, msg, body)
+			}
+		}
+	}
+}
+
+func TestAnalyse(t *testing.T) {
+	rec := postAnalysis(t, AnalysisRequest{Fen: "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", Depth: 2, Lines: 1})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var data struct {
+		Lines []struct {
+			Move string `json:"move"`
+			Mate int    `json:"mate"`
+		} `json:"lines"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+		t.Fatalf("could not decode response: %s", err)
+	}
+	if len(data.Lines) != 1 || data.Lines[0].Move != "a1a8" || data.Lines[0].Mate != 1 {
+		t.Fatalf("expected a1a8 mating in 1, got %+v", data.Lines)
+	}
+}
+
+func postClaimDraw(t *testing.T, fen string) map[string]interface{} {
+	t.Helper()
+	brd, errs := board.ParseFen(fen)
+	if len(errs) > 0 {
+		t.Fatalf("could not set up %s: %s", fen, errs[0].Message)
+	}
+	brd.Evaluate(board.ENEMY[brd.Turn])
+	Game = game.New(brd)
+
+	rec := httptest.NewRecorder()
+	claimDraw(rec, httptest.NewRequest(http.MethodPost, "/claimdraw", nil))
+	var data map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &data); err != nil {
+		t.Fatalf("could not decode response: %s", err)
+	}
+	return data
+}
+
+func TestClaimDraw(t *testing.T) {
+	data := postClaimDraw(t, "4k3/8/8/8/8/8/4P3/R3K3 w - - 100 80")
+	if data["type"] != "DRAW" || data["msg"] != board.DRAW_FIFTY_MOVES {
+		t.Fatalf("expected a draw by the fifty-move rule, got %v", data)
+	}
+
+	data = postClaimDraw(t, "4k3/8/8/8/8/8/4P3/R3K3 w - - 99 80")
+	if data["valid"] != false {
+		t.Fatalf("expected the claim to be refused, got %v", data)
+	}
+}
@@ -143,14 +143,9 @@ function sendMove() {
   const url = "http://localhost:3435/usermove";
 
   let move = {
-    from: userMove["from"],
-    to: userMove["to"],
-    promotion: "",
+    move: toUci(userMove),
   };
 
-  if (canPromotePawn(userMove)) {
-    move["promotion"] = "QUEEN";
-  }
   const fetchOptions = {
     method: "POST",
     headers: {
@@ -179,6 +174,28 @@ function sendMove() {
     });
 }
 
+function toUci(move) {
+  let from = cols[move["from"][1]] + rows[move["from"][0]];
+  let to = cols[move["to"][1]] + rows[move["to"][0]];
+  let uci = from + to;
+  if (canPromotePawn(move)) {
+    uci += choosePromotion();
+  }
+  return uci;
+}
+
+function choosePromotion() {
+  let choice = window.prompt("Promote to (q, r, b, n):", "q");
+  if (choice === null) {
+    return "q";
+  }
+  choice = choice.trim().toLowerCase();
+  if (["q", "r", "b", "n"].includes(choice)) {
+    return choice;
+  }
+  return "q";
+}
+
 function canPromotePawn(move) {
   let piece = getFromPiece(move);
   if (piece.dataset.name === "PAWN") {
@@ -219,6 +236,13 @@ function getBotMove() {
     .then((data) => {
       console.log(data["receipt"]);
       console.log(data["fen"]);
+      if (data["pv"]) {
+        console.log("expected line: " + data["pv"].join(" "));
+      }
+      if (data["mate"]) {
+        const winner = data["mate"] > 0 ? "WHITE" : "BLACK";
+        console.log(winner + " mates in " + Math.abs(data["mate"]));
+      }
 
       if (data["type"] === "STALEMATE") {
         let msg = "Stalemate\n Game is a draw";
@@ -243,7 +267,7 @@ function getBotMove() {
         alert(msg);
       }
       if (data["draw"] === true) {
-        let msg = "Draw\n" + data["msg"];
+        let msg = "Draw\n" + data["draw-type"];
         alert(msg);
       }
     })
@@ -277,8 +301,8 @@ function updateBoard(data) {
   toSq.removeChild(toPiece);
   fromSq.appendChild(nullPiece);
 
-  if (data["promotion"] === "QUEEN") {
-    handlePawnPromotion(toSq, fromPiece);
+  if (data["promotion"]) {
+    handlePawnPromotion(toSq, fromPiece, data["promotion"]);
   }
 
   updateEvalBar(data["eval"]);
@@ -291,22 +315,18 @@ function updateEvalBar(eval) {
   blackBar.style.height = height + "%";
 }
 
-function handlePawnPromotion(toSq, fromPiece) {
+function handlePawnPromotion(toSq, fromPiece, name) {
   let color = fromPiece.dataset.color;
-  var image;
-  if (color === "white") {
-    image = `url("static/pieces/queen-w.svg")`;
-  } else {
-    image = `url("static/pieces/queen-b.svg")`;
-  }
-  let queen = createPiece(image, color, "QUEEN");
-  queen.addEventListener("click", function () {
-    selectSquare(queen);
+  let suffix = color === "white" ? "w" : "b";
+  let image = `url("static/pieces/${name.toLowerCase()}-${suffix}.svg")`;
+  let promoted = createPiece(image, color, name);
+  promoted.addEventListener("click", function () {
+    selectSquare(promoted);
   });
-  queen.style.cursor = "pointer";
+  promoted.style.cursor = "pointer";
 
   toSq.removeChild(toSq.firstChild);
-  toSq.appendChild(queen);
+  toSq.appendChild(promoted);
 }
 
 function handleEnPassant(from, to) {